@@ -2,24 +2,43 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/net/http2"
+	"golang.org/x/time/rate"
 )
 
 // PostRequest represents the request body for creating a post
 type PostRequest struct {
-	Timestamp     string `json:"timestamp"`
-	SatelliteName string `json:"satellite_name"`
-	Metadata      string `json:"metadata,omitempty"`
+	Timestamp       string  `json:"timestamp"`
+	SatelliteName   string  `json:"satellite_name"`
+	Metadata        string  `json:"metadata,omitempty"`
+	TLE1            string  `json:"tle1,omitempty"`
+	TLE2            string  `json:"tle2,omitempty"`
+	Instrument      string  `json:"instrument,omitempty"`
+	NORAD           int     `json:"norad,omitempty"`
+	FrequencyMHz    float64 `json:"frequency_mhz,omitempty"`
+	DurationSeconds int     `json:"duration_seconds"`
+	IdempotencyKey  string  `json:"idempotency_key,omitempty"` // SHA-256 of dataset.json
 }
 
 // PostResponse represents the API response for a created post
@@ -30,7 +49,11 @@ type PostResponse struct {
 	Timestamp     string          `json:"timestamp"`
 	SatelliteName string          `json:"satellite_name"`
 	Metadata      string          `json:"metadata"`
+	NORAD         int             `json:"norad,omitempty"`
+	FrequencyMHz  float64         `json:"frequency_mhz,omitempty"`
 	Images        []ImageResponse `json:"images"`
+	HasCBOR       bool            `json:"has_cbor,omitempty"`
+	HasCADU       bool            `json:"has_cadu,omitempty"`
 	CreatedAt     string          `json:"created_at"`
 	UpdatedAt     string          `json:"updated_at"`
 }
@@ -42,33 +65,469 @@ type ImageResponse struct {
 	ImageURL string `json:"image_url"`
 }
 
+// APIError is returned when the API responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Body       string
+	RequestID  string // X-Request-ID of the failed request, to find it in the server logs
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("API returned status %d: %s (request ID %s)", e.StatusCode, e.Body, e.RequestID)
+	}
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError creates the APIError for resp with its already read body
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	if resp.Request != nil {
+		apiErr.RequestID = resp.Request.Header.Get("X-Request-ID")
+	}
+	return apiErr
+}
+
+// ErrInvalidCBOR is returned by UploadCBOR for files that cannot be decoded
+var ErrInvalidCBOR = errors.New("CBOR file is corrupt")
+
+// Default APIClient timeouts
+const (
+	defaultConnectTimeout     = 10 * time.Second
+	defaultHealthCheckTimeout = 10 * time.Second
+	defaultUploadTimeoutPerMB = 5 * time.Second
+)
+
 // APIClient handles communication with the SatHub API
 type APIClient struct {
 	baseURL      string
 	stationToken string
+	UserAgent    string // Sent with every request, defaults to defaultUserAgent()
+	StationID    string // Sent as X-Station-ID so server access logs show the station, empty until known
 	httpClient   *http.Client
+	transport    *http.Transport
+	uploadRate   int64 // Max upload bytes per second per upload, 0 for unlimited
+	dryRun       bool  // Log requests instead of sending data
+	validateCBOR bool  // Decode CBOR files before uploading them
+
+	connectTimeout     time.Duration // Dial and TLS handshake
+	healthCheckTimeout time.Duration // Whole request for small JSON calls
+	uploadTimeoutPerMB time.Duration // Added to connectTimeout per started megabyte of an upload
+
+	errorCount int64 // Failed requests since the last successful health check, accessed atomically
+
+	startTime time.Time    // Reported as uptime in health checks
+	watcher   *FileWatcher // Optional, reports watch paths and processed passes in health checks
+
+	rateLimitMu      sync.Mutex
+	rateLimited      int       // Consecutive 429 responses
+	circuitOpenUntil time.Time // Requests fail fast until this time after repeated 429s
+	circuitBreaker   CircuitBreakerConfig
+}
+
+const (
+	// rateLimitBackoff is the default initial wait after a 429 response without a Retry-After header
+	rateLimitBackoff = 5 * time.Second
+
+	// rateLimitCircuitThreshold is the default number of consecutive 429 responses after which
+	// requests fail fast until the server's back-off period has passed
+	rateLimitCircuitThreshold = 3
+
+	// transportRetryAttempts is how often a request is repeated after the connection failed, see isRetryableError
+	transportRetryAttempts = 3
+
+	// transportRetryDelay is the wait before the first repeat, doubled for each further one
+	transportRetryDelay = 2 * time.Second
+)
+
+// NewAPIClientWithInsecure creates a new API client that optionally skips certificate verification.
+//
+// Deprecated: use NewAPIClient with WithInsecure.
+func NewAPIClientWithInsecure(baseURL, stationToken string, insecure bool) *APIClient {
+	return NewAPIClient(baseURL, stationToken, WithInsecure(insecure))
 }
 
-// NewAPIClient creates a new API client
-func NewAPIClient(baseURL, stationToken string, insecure bool) *APIClient {
+// NewAPIClient creates a new API client configured by opts
+func NewAPIClient(baseURL, stationToken string, opts ...APIClientOption) *APIClient {
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: insecure,
-		},
+		TLSClientConfig: &tls.Config{},
 	}
 
-	return &APIClient{
+	c := &APIClient{
 		baseURL:      strings.TrimSuffix(baseURL, "/"),
 		stationToken: stationToken,
+		UserAgent:    defaultUserAgent(),
+		// Requests are bounded by per-operation context deadlines instead of a client timeout
 		httpClient: &http.Client{
-			Timeout:   30 * time.Second,
 			Transport: transport,
 		},
+		transport:          transport,
+		connectTimeout:     defaultConnectTimeout,
+		healthCheckTimeout: defaultHealthCheckTimeout,
+		uploadTimeoutPerMB: defaultUploadTimeoutPerMB,
+		startTime:          time.Now(),
+		circuitBreaker: CircuitBreakerConfig{
+			Threshold: rateLimitCircuitThreshold,
+			Backoff:   rateLimitBackoff,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// A custom TLSClientConfig disables the automatic HTTP/2 upgrade, enable it explicitly
+	// so concurrent uploads are multiplexed over a single connection
+	if err := http2.ConfigureTransport(transport); err != nil {
+		logger.Warn().Err(err).Msg("Failed to enable HTTP/2, falling back to HTTP/1.1")
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer := net.Dialer{Timeout: c.connectTimeout, KeepAlive: 30 * time.Second}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	transport.TLSHandshakeTimeout = c.connectTimeout
+
+	return c
+}
+
+// defaultUserAgent identifies the client version and platform, e.g. sathub-client/1.2.4 (linux/amd64; go1.21.5)
+func defaultUserAgent() string {
+	return fmt.Sprintf("sathub-client/%s (%s/%s; %s)", VERSION, runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
+
+// setDefaultHeaders sets the headers sent with every request, the station token, the user agent,
+// the station ID once known and a new request ID that is kept when the request is retried
+func (c *APIClient) setDefaultHeaders(req *http.Request) {
+	req.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("X-Request-ID", NewRequestID())
+	if c.StationID != "" {
+		req.Header.Set("X-Station-ID", c.StationID)
+	}
+}
+
+// NewRequestID returns a random version 4 UUID used as X-Request-ID
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand only fails without an OS entropy source, fall back to a time based value
+		return fmt.Sprintf("00000000-0000-4000-8000-%012x", time.Now().UnixNano()&0xffffffffffff)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SetTimeouts sets the connect timeout, the timeout for small JSON requests and the
+// upload time allowed per megabyte, zero values keep the defaults
+func (c *APIClient) SetTimeouts(connect, healthCheck, uploadPerMB time.Duration) {
+	if connect > 0 {
+		c.connectTimeout = connect
+		c.transport.TLSHandshakeTimeout = connect
+	}
+	if healthCheck > 0 {
+		c.healthCheckTimeout = healthCheck
+	}
+	if uploadPerMB > 0 {
+		c.uploadTimeoutPerMB = uploadPerMB
+	}
+}
+
+// uploadTimeout returns the deadline for uploading size bytes, including time spent throttled
+func (c *APIClient) uploadTimeout(size int64) time.Duration {
+	megabytes := (size + 1<<20 - 1) >> 20
+	timeout := c.connectTimeout + time.Duration(megabytes)*c.uploadTimeoutPerMB
+	if c.uploadRate > 0 {
+		timeout += time.Duration(size/c.uploadRate) * time.Second
+	}
+	return timeout
+}
+
+// ConfigureTLS adds a custom CA certificate and a client certificate for mutual TLS
+func (c *APIClient) ConfigureTLS(caCertPath, clientCertPath, clientKeyPath string) error {
+	return applyTLSFiles(c.transport.TLSClientConfig, caCertPath, clientCertPath, clientKeyPath)
+}
+
+// SetUploadRateLimit limits each file upload to bytesPerSecond, 0 disables throttling
+func (c *APIClient) SetUploadRateLimit(bytesPerSecond int64) {
+	c.uploadRate = bytesPerSecond
+}
+
+// SetValidateCBOR makes UploadCBOR decode each file first and refuse corrupt ones with ErrInvalidCBOR
+func (c *APIClient) SetValidateCBOR(validate bool) {
+	c.validateCBOR = validate
+}
+
+// SetDryRun makes post creation and uploads log what they would send instead of sending it
+func (c *APIClient) SetDryRun(dryRun bool) {
+	c.dryRun = dryRun
+}
+
+// logDryRun logs a request that was not sent because dry-run mode is enabled
+func (c *APIClient) logDryRun(req *http.Request, size int, contentType string) {
+	logger.Info().
+		Str("method", req.Method).
+		Str("url", req.URL.String()).
+		Int("size", size).
+		Str("content_type", contentType).
+		Msg("[dry-run] Would send request")
+}
+
+// throttle wraps an upload body in a rate limited reader if a limit is configured.
+// Each upload gets its own limiter so concurrent uploads stack, waiting stops when ctx is done.
+func (c *APIClient) throttle(ctx context.Context, r io.Reader) io.Reader {
+	if c.uploadRate <= 0 {
+		return r
+	}
+	return &throttledReader{
+		ctx:     ctx,
+		reader:  r,
+		limiter: rate.NewLimiter(rate.Limit(c.uploadRate), int(c.uploadRate)),
+	}
+}
+
+// throttledReader is an io.Reader that blocks to stay within the limiter's rate
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rate.Limiter
+}
+
+// Read reads at most one burst of bytes and waits until the limiter allows them
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := t.reader.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.WaitN(t.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// newUploadRequest creates a throttled POST request for a multipart body that can be resent on retry
+func (c *APIClient) newUploadRequest(ctx context.Context, url string, buf *bytes.Buffer) (*http.Request, error) {
+	data := buf.Bytes()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, c.throttle(ctx, bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.ContentLength = int64(len(data))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(c.throttle(ctx, bytes.NewReader(data))), nil
+	}
+	return httpReq, nil
+}
+
+// newStreamingUploadRequest creates a throttled POST request whose multipart body is streamed from the
+// file at path through a pipe, so large files are never held in memory. The file is reopened on retry.
+func (c *APIClient) newStreamingUploadRequest(ctx context.Context, url, field, path, contentType string) (*http.Request, error) {
+	form := multipart.NewWriter(io.Discard)
+	getBody := func() (io.ReadCloser, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			defer file.Close()
+			writer := multipart.NewWriter(pw)
+			writer.SetBoundary(form.Boundary())
+
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, filepath.Base(path)))
+			h.Set("Content-Type", contentType)
+			part, err := writer.CreatePart(h)
+			if err == nil {
+				_, err = io.Copy(part, file)
+			}
+			if err == nil {
+				err = writer.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		// Closing the reader stops the writing goroutine when the request is aborted
+		return struct {
+			io.Reader
+			io.Closer
+		}{c.throttle(ctx, pr), pr}, nil
+	}
+
+	body, err := getBody()
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		body.Close()
+		return nil, err
 	}
+	httpReq.GetBody = getBody
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+	return httpReq, nil
+}
+
+// doWithRetry sends the request, waiting and retrying when the server responds with 429.
+// The wait honours the Retry-After header and falls back to exponential back-off.
+func (c *APIClient) doWithRetry(req *http.Request) (*http.Response, error) {
+	transportFailures := 0
+	for {
+		c.rateLimitMu.Lock()
+		openUntil := c.circuitOpenUntil
+		c.rateLimitMu.Unlock()
+		if time.Now().Before(openUntil) {
+			return nil, fmt.Errorf("rate limited by API, requests paused until %s", openUntil.Format(time.RFC3339))
+		}
+
+		requestID := req.Header.Get("X-Request-ID")
+		logger.Debug().
+			Str("method", req.Method).
+			Str("url", req.URL.String()).
+			Str("request_id", requestID).
+			Msg("Sending API request")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			atomic.AddInt64(&c.errorCount, 1)
+			// Retrying is pointless once the deadline of the whole operation has passed
+			canRetry := req.Context().Err() == nil && (req.Body == nil || req.GetBody != nil)
+			if !canRetry || transportFailures >= transportRetryAttempts || !isRetryableError(err) {
+				return nil, fmt.Errorf("failed to send request (request ID %s): %w", requestID, err)
+			}
+			transportFailures++
+			wait := transportRetryDelay << (transportFailures - 1)
+			logger.Warn().
+				Err(err).
+				Str("url", req.URL.String()).
+				Str("request_id", requestID).
+				Int("attempt", transportFailures).
+				Dur("retry_in", wait).
+				Msg("Connection to API failed, retrying")
+
+			select {
+			case <-time.After(wait):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			if req, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if echoed := resp.Header.Get("X-Request-ID"); echoed != "" && echoed != requestID {
+			logger.Warn().
+				Str("request_id", requestID).
+				Str("response_request_id", echoed).
+				Str("url", req.URL.String()).
+				Msg("API responded with a different request ID")
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			c.rateLimitMu.Lock()
+			c.rateLimited = 0
+			c.rateLimitMu.Unlock()
+			if resp.StatusCode >= http.StatusBadRequest {
+				atomic.AddInt64(&c.errorCount, 1)
+			}
+			return resp, nil
+		}
+		atomic.AddInt64(&c.errorCount, 1)
+
+		retryAfter := resp.Header.Get("Retry-After")
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		c.rateLimitMu.Lock()
+		c.rateLimited++
+		consecutive := c.rateLimited
+		wait, ok := parseRetryAfter(retryAfter, time.Now())
+		if !ok {
+			wait = c.circuitBreaker.Backoff << (consecutive - 1)
+		}
+		if consecutive >= c.circuitBreaker.Threshold {
+			c.circuitOpenUntil = time.Now().Add(wait)
+		}
+		c.rateLimitMu.Unlock()
+
+		logger.Warn().
+			Str("url", req.URL.String()).
+			Str("retry_after", retryAfter).
+			Dur("wait", wait).
+			Int("consecutive", consecutive).
+			Msg("Rate limited by API")
+
+		if consecutive >= c.circuitBreaker.Threshold {
+			return nil, fmt.Errorf("rate limited by API: %w", newAPIError(resp, body))
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewindRequest returns a copy of req with a fresh body for the next attempt
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset request body: %w", err)
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// isRetryableError reports whether a request that failed without a response may succeed when sent again,
+// e.g. after the server or a proxy dropped the connection in the middle of a large upload
+func isRetryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe")
+}
+
+// ErrorCount returns the number of failed requests since the last successful health check
+func (c *APIClient) ErrorCount() int {
+	return int(atomic.LoadInt64(&c.errorCount))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
 }
 
 // CreatePost sends a post creation request to the API
-func (c *APIClient) CreatePost(req PostRequest) (*PostResponse, error) {
+func (c *APIClient) CreatePost(ctx context.Context, req PostRequest) (*PostResponse, error) {
 	url := fmt.Sprintf("%s/api/posts", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -76,23 +535,39 @@ func (c *APIClient) CreatePost(req PostRequest) (*PostResponse, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	ctx, cancel := context.WithTimeout(ctx, c.healthCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	c.setDefaultHeaders(httpReq)
+	if req.IdempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
+	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	if c.dryRun {
+		c.logDryRun(httpReq, len(jsonData), "application/json")
+		return &PostResponse{
+			ID:            fmt.Sprintf("dry-run-%d", time.Now().Unix()),
+			Timestamp:     req.Timestamp,
+			SatelliteName: req.SatelliteName,
+			Metadata:      req.Metadata,
+		}, nil
+	}
+
+	resp, err := c.doWithRetry(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API request failed: %w", newAPIError(resp, body))
 	}
 
 	var apiResp struct {
@@ -106,8 +581,128 @@ func (c *APIClient) CreatePost(req PostRequest) (*PostResponse, error) {
 	return &apiResp.Data, nil
 }
 
-// UploadImage uploads an image for a post
-func (c *APIClient) UploadImage(postID string, imagePath string) error {
+// GetPost fetches a post by ID
+func (c *APIClient) GetPost(ctx context.Context, postID string) (*PostResponse, error) {
+	url := fmt.Sprintf("%s/api/posts/%s", c.baseURL, postID)
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setDefaultHeaders(httpReq)
+
+	resp, err := c.doWithRetry(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get post: %w", newAPIError(resp, body))
+	}
+
+	var apiResp struct {
+		Data PostResponse `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &apiResp.Data, nil
+}
+
+// ListPosts fetches the most recent posts of the station, newest first
+func (c *APIClient) ListPosts(ctx context.Context, limit int) ([]PostResponse, error) {
+	url := fmt.Sprintf("%s/api/posts?limit=%d", c.baseURL, limit)
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setDefaultHeaders(httpReq)
+
+	resp, err := c.doWithRetry(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list posts: %w", newAPIError(resp, body))
+	}
+
+	var apiResp struct {
+		Data []PostResponse `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return apiResp.Data, nil
+}
+
+// DeletePost deletes a post by ID
+func (c *APIClient) DeletePost(ctx context.Context, postID string) error {
+	url := fmt.Sprintf("%s/api/posts/%s", c.baseURL, postID)
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setDefaultHeaders(httpReq)
+
+	if c.dryRun {
+		c.logDryRun(httpReq, 0, "")
+		return nil
+	}
+
+	resp, err := c.doWithRetry(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete post: %w", newAPIError(resp, body))
+	}
+
+	return nil
+}
+
+// mimeTypeForExtension returns the MIME type of well-known image extensions, or an empty
+// string when the type has to be detected from the file content with http.DetectContentType
+func mimeTypeForExtension(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".png":
+		return "image/png"
+	case ".tif", ".tiff":
+		return "image/tiff"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	default:
+		return ""
+	}
+}
+
+// UploadImage uploads an image for a post, tagged with the product directory it came from
+func (c *APIClient) UploadImage(ctx context.Context, postID string, imagePath string, productName string) error {
 	url := fmt.Sprintf("%s/api/posts/%s/images", c.baseURL, postID)
 
 	file, err := os.Open(imagePath)
@@ -116,17 +711,20 @@ func (c *APIClient) UploadImage(postID string, imagePath string) error {
 	}
 	defer file.Close()
 
-	// Read first 512 bytes to detect content type
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("failed to read file header: %w", err)
-	}
-	contentType := http.DetectContentType(buffer[:n])
-
-	// Reset file pointer to beginning
-	if _, err := file.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to reset file pointer: %w", err)
+	// Sniffing misidentifies TIFFs, so only detect the content type for unknown extensions
+	contentType := mimeTypeForExtension(filepath.Ext(imagePath))
+	if contentType == "" {
+		buffer := make([]byte, 512)
+		n, err := file.Read(buffer)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read file header: %w", err)
+		}
+		contentType = http.DetectContentType(buffer[:n])
+
+		// Reset file pointer to beginning
+		if _, err := file.Seek(0, 0); err != nil {
+			return fmt.Errorf("failed to reset file pointer: %w", err)
+		}
 	}
 
 	var buf bytes.Buffer
@@ -146,32 +744,46 @@ func (c *APIClient) UploadImage(postID string, imagePath string) error {
 		return fmt.Errorf("failed to copy file data: %w", err)
 	}
 
+	if productName != "" {
+		if err := writer.WriteField("product", productName); err != nil {
+			return fmt.Errorf("failed to write product field: %w", err)
+		}
+	}
+
 	writer.Close()
 
-	httpReq, err := http.NewRequest("POST", url, &buf)
+	ctx, cancel := context.WithTimeout(ctx, c.uploadTimeout(int64(buf.Len())))
+	defer cancel()
+
+	httpReq, err := c.newUploadRequest(ctx, url, &buf)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	c.setDefaultHeaders(httpReq)
+
+	if c.dryRun {
+		c.logDryRun(httpReq, buf.Len(), contentType)
+		return nil
+	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.doWithRetry(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("image upload failed with status %d: %s", resp.StatusCode, string(body))
+		return &UploadError{FileType: "image", FilePath: imagePath, Cause: newAPIError(resp, body)}
 	}
 
 	return nil
 }
 
 // UploadCBOR uploads a CBOR file for a post
-func (c *APIClient) UploadCBOR(postID string, cborPath string) error {
+func (c *APIClient) UploadCBOR(ctx context.Context, postID string, cborPath string) error {
 	url := fmt.Sprintf("%s/api/posts/%s/cbor", c.baseURL, postID)
 
 	file, err := os.Open(cborPath)
@@ -180,10 +792,20 @@ func (c *APIClient) UploadCBOR(postID string, cborPath string) error {
 	}
 	defer file.Close()
 
+	// A CBOR file truncated by a crashed SatDump is rejected by the server with a confusing error
+	if c.validateCBOR {
+		if err := cbor.NewDecoder(file).Decode(&SatDumpProduct{}); err != nil {
+			return &UploadError{FileType: "cbor", FilePath: cborPath, Cause: fmt.Errorf("%w: %w", ErrInvalidCBOR, err)}
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to reset file pointer: %w", err)
+		}
+	}
+
 	// Read first 512 bytes to detect content type (though CBOR is application/cbor)
 	buffer := make([]byte, 512)
 	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	if err != nil && !errors.Is(err, io.EOF) {
 		return fmt.Errorf("failed to read file header: %w", err)
 	}
 	contentType := http.DetectContentType(buffer[:n])
@@ -216,30 +838,38 @@ func (c *APIClient) UploadCBOR(postID string, cborPath string) error {
 
 	writer.Close()
 
-	httpReq, err := http.NewRequest("POST", url, &buf)
+	ctx, cancel := context.WithTimeout(ctx, c.uploadTimeout(int64(buf.Len())))
+	defer cancel()
+
+	httpReq, err := c.newUploadRequest(ctx, url, &buf)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	c.setDefaultHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	if c.dryRun {
+		c.logDryRun(httpReq, buf.Len(), contentType)
+		return nil
+	}
+
+	resp, err := c.doWithRetry(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("CBOR upload failed with status %d: %s", resp.StatusCode, string(body))
+		return &UploadError{FileType: "cbor", FilePath: cborPath, Cause: newAPIError(resp, body)}
 	}
 
 	return nil
 }
 
 // UploadCADU uploads a CADU file for a post
-func (c *APIClient) UploadCADU(postID string, caduPath string) error {
+func (c *APIClient) UploadCADU(ctx context.Context, postID string, caduPath string) error {
 	url := fmt.Sprintf("%s/api/posts/%s/cadu", c.baseURL, postID)
 
 	file, err := os.Open(caduPath)
@@ -251,7 +881,7 @@ func (c *APIClient) UploadCADU(postID string, caduPath string) error {
 	// Read first 512 bytes to detect content type (though CADU is application/octet-stream)
 	buffer := make([]byte, 512)
 	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	if err != nil && !errors.Is(err, io.EOF) {
 		return fmt.Errorf("failed to read file header: %w", err)
 	}
 	contentType := http.DetectContentType(buffer[:n])
@@ -282,56 +912,152 @@ func (c *APIClient) UploadCADU(postID string, caduPath string) error {
 
 	writer.Close()
 
-	httpReq, err := http.NewRequest("POST", url, &buf)
+	ctx, cancel := context.WithTimeout(ctx, c.uploadTimeout(int64(buf.Len())))
+	defer cancel()
+
+	httpReq, err := c.newUploadRequest(ctx, url, &buf)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	c.setDefaultHeaders(httpReq)
+
+	if c.dryRun {
+		c.logDryRun(httpReq, buf.Len(), contentType)
+		return nil
+	}
+
+	resp, err := c.doWithRetry(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return &UploadError{FileType: "cadu", FilePath: caduPath, Cause: newAPIError(resp, body)}
+	}
+
+	return nil
+}
+
+// UploadIQ uploads a raw I/Q recording for a post. Recordings can be several gigabytes,
+// so the file is streamed instead of being buffered like the other uploads.
+func (c *APIClient) UploadIQ(ctx context.Context, postID string, iqPath string) error {
+	url := fmt.Sprintf("%s/api/posts/%s/iq", c.baseURL, postID)
+
+	info, err := os.Stat(iqPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat IQ file: %w", err)
+	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	ctx, cancel := context.WithTimeout(ctx, c.uploadTimeout(info.Size()))
+	defer cancel()
+
+	contentType := "application/octet-stream"
+	httpReq, err := c.newStreamingUploadRequest(ctx, url, "iq", iqPath, contentType)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setDefaultHeaders(httpReq)
+
+	if c.dryRun {
+		httpReq.Body.Close()
+		c.logDryRun(httpReq, int(info.Size()), contentType)
+		return nil
+	}
+
+	resp, err := c.doWithRetry(httpReq)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("CADU upload failed with status %d: %s", resp.StatusCode, string(body))
+		return &UploadError{FileType: "iq", FilePath: iqPath, Cause: newAPIError(resp, body)}
 	}
 
 	return nil
 }
 
+// ServerSettings represents the station settings returned by the server
+// (mirrors SettingsUpdatePayload)
+type ServerSettings struct {
+	HealthCheckInterval int `json:"health_check_interval,omitempty"` // seconds
+	ProcessDelay        int `json:"process_delay,omitempty"`         // seconds
+}
+
 // HealthResponse represents the response from a health check
 type HealthResponse struct {
-	Status    string                 `json:"status"`
-	StationID string                 `json:"station_id"`
-	Timestamp string                 `json:"timestamp"`
-	Settings  map[string]interface{} `json:"settings,omitempty"`
+	Status      string         `json:"status"`
+	StationID   string         `json:"station_id"`
+	StationName string         `json:"station_name,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+	Settings    ServerSettings `json:"settings,omitempty"`
+}
+
+// HealthRequest describes the state of the client in a health check, so the server can spot outdated
+// or misconfigured clients. Counts are -1 when unknown, e.g. for commands that don't run a watcher.
+type HealthRequest struct {
+	Version        string `json:"version"`
+	UptimeSeconds  int64  `json:"uptime_seconds"`
+	WatchPaths     int    `json:"watch_paths"`
+	ProcessedCount int    `json:"processed_count"`
+}
+
+// SetWatcher sets the watcher whose watch paths and processed pass count are reported in health checks
+func (c *APIClient) SetWatcher(watcher *FileWatcher) {
+	c.watcher = watcher
+}
+
+// healthRequest returns the current state of the client for a health check
+func (c *APIClient) healthRequest() HealthRequest {
+	req := HealthRequest{
+		Version:        VERSION,
+		UptimeSeconds:  int64(time.Since(c.startTime).Seconds()),
+		WatchPaths:     -1,
+		ProcessedCount: -1,
+	}
+	if c.watcher != nil {
+		req.WatchPaths = c.watcher.WatchPathCount()
+		if count, err := c.watcher.ProcessedPassCount(); err == nil {
+			req.ProcessedCount = count
+		}
+	}
+	return req
 }
 
 // StationHealth sends a health check to update station last seen and returns settings
-func (c *APIClient) StationHealth() (*HealthResponse, error) {
+func (c *APIClient) StationHealth(ctx context.Context) (*HealthResponse, error) {
 	url := fmt.Sprintf("%s/api/stations/health", c.baseURL)
 
-	httpReq, err := http.NewRequest("POST", url, nil)
+	jsonData, err := json.Marshal(c.healthRequest())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.healthCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Station %s", c.stationToken))
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.setDefaultHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.doWithRetry(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("health check failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("health check failed: %w", newAPIError(resp, body))
 	}
 
 	var healthResp struct {
@@ -342,5 +1068,6 @@ func (c *APIClient) StationHealth() (*HealthResponse, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	atomic.StoreInt64(&c.errorCount, 0)
 	return &healthResp.Data, nil
 }
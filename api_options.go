@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// APIClientOption configures an APIClient created by NewAPIClient
+type APIClientOption func(*APIClient)
+
+// CircuitBreakerConfig controls how the client backs off when the API keeps answering 429 Too Many Requests
+type CircuitBreakerConfig struct {
+	Threshold int           // Consecutive 429 responses after which requests fail fast until the back-off has passed
+	Backoff   time.Duration // Initial wait after a 429 response without Retry-After, doubled for each further one
+}
+
+// WithInsecure disables verification of the server certificate
+func WithInsecure(insecure bool) APIClientOption {
+	return func(c *APIClient) {
+		c.transport.TLSClientConfig.InsecureSkipVerify = insecure
+	}
+}
+
+// WithTLSConfig replaces the TLS configuration of the client, e.g. to trust a private CA.
+// Options applied after it, such as WithInsecure, modify the copy made of config.
+func WithTLSConfig(config *tls.Config) APIClientOption {
+	return func(c *APIClient) {
+		if config != nil {
+			c.transport.TLSClientConfig = config.Clone()
+		}
+	}
+}
+
+// WithTimeout sets the deadline of small JSON requests such as health checks and post creation,
+// uploads get more time depending on their size, see SetTimeouts
+func WithTimeout(timeout time.Duration) APIClientOption {
+	return func(c *APIClient) {
+		if timeout > 0 {
+			c.healthCheckTimeout = timeout
+		}
+	}
+}
+
+// WithRateLimit limits each file upload to bytesPerSecond, 0 disables throttling
+func WithRateLimit(bytesPerSecond float64) APIClientOption {
+	return func(c *APIClient) {
+		c.uploadRate = int64(bytesPerSecond)
+	}
+}
+
+// WithUserAgent replaces the User-Agent sent with every request
+func WithUserAgent(userAgent string) APIClientOption {
+	return func(c *APIClient) {
+		if userAgent != "" {
+			c.UserAgent = userAgent
+		}
+	}
+}
+
+// WithStationID sends id as X-Station-ID with every request
+func WithStationID(id string) APIClientOption {
+	return func(c *APIClient) {
+		c.StationID = id
+	}
+}
+
+// WithCircuitBreaker changes the back-off after 429 responses, zero fields keep the defaults
+func WithCircuitBreaker(breaker CircuitBreakerConfig) APIClientOption {
+	return func(c *APIClient) {
+		if breaker.Threshold > 0 {
+			c.circuitBreaker.Threshold = breaker.Threshold
+		}
+		if breaker.Backoff > 0 {
+			c.circuitBreaker.Backoff = breaker.Backoff
+		}
+	}
+}
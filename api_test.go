@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// dropFirstServer starts a server that reads the body of the first request and then drops the connection
+// without responding, later requests are passed to handler. It returns the server and the bodies received.
+func dropFirstServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body []byte)) (*httptest.Server, func() [][]byte, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	var requestIDs []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+
+		mu.Lock()
+		bodies = append(bodies, body)
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		attempt := len(bodies)
+		mu.Unlock()
+
+		if attempt == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("failed to hijack connection: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		handler(w, r, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	received := func() [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]byte(nil), bodies...)
+	}
+	ids := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requestIDs...)
+	}
+	return srv, received, ids
+}
+
+func TestDoWithRetryReplaysJSONBody(t *testing.T) {
+	srv, received, requestIDs := dropFirstServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"data":{"id":"post-1"}}`)
+	})
+
+	client := NewAPIClient(srv.URL, "token")
+	post, err := client.CreatePost(context.Background(), PostRequest{
+		Timestamp:     "2024-01-01T00:00:00Z",
+		SatelliteName: "NOAA 19",
+	})
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if post.ID != "post-1" {
+		t.Errorf("post ID = %q, want post-1", post.ID)
+	}
+
+	bodies := received()
+	if len(bodies) != 2 {
+		t.Fatalf("server received %d requests, want 2", len(bodies))
+	}
+	if string(bodies[0]) != string(bodies[1]) {
+		t.Errorf("retried body %q differs from the first attempt %q", bodies[1], bodies[0])
+	}
+	var sent PostRequest
+	if err := json.Unmarshal(bodies[1], &sent); err != nil {
+		t.Fatalf("retried body is not valid JSON: %v", err)
+	}
+	if sent.SatelliteName != "NOAA 19" {
+		t.Errorf("retried satellite name = %q, want NOAA 19", sent.SatelliteName)
+	}
+
+	ids := requestIDs()
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("request IDs %q and %q should be equal and non-empty", ids[0], ids[1])
+	}
+}
+
+func TestDoWithRetryReplaysMultipartUpload(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("image data that has to be sent twice")
+	imagePath := filepath.Join(dir, "rgb.png")
+	if err := os.WriteFile(imagePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	iqPath := filepath.Join(dir, "baseband.raw")
+	if err := os.WriteFile(iqPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		upload func(c *APIClient) error
+	}{
+		{
+			name:   "buffered image",
+			field:  "image",
+			upload: func(c *APIClient) error { return c.UploadImage(context.Background(), "post-1", imagePath, "MSU-MR") },
+		},
+		{
+			name:   "streamed IQ",
+			field:  "iq",
+			upload: func(c *APIClient) error { return c.UploadIQ(context.Background(), "post-1", iqPath) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parsed []byte
+			srv, received, _ := dropFirstServer(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				file, _, err := r.FormFile(tt.field)
+				if err != nil {
+					t.Errorf("retried request has no %s part: %v", tt.field, err)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				defer file.Close()
+				parsed, _ = io.ReadAll(file)
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			if err := tt.upload(NewAPIClient(srv.URL, "token")); err != nil {
+				t.Fatalf("upload failed: %v", err)
+			}
+
+			bodies := received()
+			if len(bodies) != 2 {
+				t.Fatalf("server received %d requests, want 2", len(bodies))
+			}
+			if string(bodies[0]) != string(bodies[1]) {
+				t.Errorf("retried multipart body differs from the first attempt")
+			}
+			if string(parsed) != string(content) {
+				t.Errorf("retried %s part = %q, want %q", tt.field, parsed, content)
+			}
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"EOF", io.EOF, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"wrapped EOF", fmt.Errorf("Post: %w", io.EOF), true},
+		{"timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"broken pipe", &net.OpError{Op: "write", Err: syscall.EPIPE}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, false},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("certificate signed by unknown authority"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottledReaderStopsWhenContextIsDone(t *testing.T) {
+	client := NewAPIClient("http://localhost", "token", WithRateLimit(10))
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := client.throttle(ctx, bytes.NewReader(make([]byte, 100)))
+
+	buf := make([]byte, 100)
+	if n, err := reader.Read(buf); err != nil || n != 10 {
+		t.Fatalf("first read = %d, %v, want one burst of 10 bytes", n, err)
+	}
+
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.Read(buf)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("read after cancel returned %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("read kept waiting for the rate limiter after the context was canceled")
+	}
+}
+
+func TestAPIClientUsesHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		fmt.Fprint(w, `{"data":{"status":"ok","station_id":"station-1"}}`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := NewAPIClient(srv.URL, "token", WithTLSConfig(srv.Client().Transport.(*http.Transport).TLSClientConfig))
+	req, err := http.NewRequest("GET", srv.URL+"/api/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.doWithRetry(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("response protocol = %s, want HTTP/2", resp.Proto)
+	}
+	if proto := resp.Header.Get("X-Proto"); proto != "HTTP/2.0" {
+		t.Errorf("server saw protocol %q, want HTTP/2.0", proto)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewRequestID()
+		if len(id) != 36 {
+			t.Fatalf("request ID %q has %d characters, want 36", id, len(id))
+		}
+		if !uuid.MatchString(id) {
+			t.Fatalf("request ID %q is not a version 4 UUID with the RFC 4122 variant", id)
+		}
+		if seen[id] {
+			t.Fatalf("request ID %q was generated twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestAPIErrorIncludesRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "invalid satellite")
+	}))
+	defer srv.Close()
+
+	_, err := NewAPIClient(srv.URL, "token").CreatePost(context.Background(), PostRequest{SatelliteName: "NOAA 19"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreatePost returned %v, want an APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Body != "invalid satellite" {
+		t.Errorf("APIError = %d %q, want 400 %q", apiErr.StatusCode, apiErr.Body, "invalid satellite")
+	}
+	if apiErr.RequestID == "" {
+		t.Fatal("APIError has no request ID")
+	}
+	if !strings.Contains(err.Error(), apiErr.RequestID) {
+		t.Errorf("error %q doesn't mention request ID %s", err, apiErr.RequestID)
+	}
+
+	withoutID := &APIError{StatusCode: http.StatusInternalServerError, Body: "oops"}
+	if got, want := withoutID.Error(), "API returned status 500: oops"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// benchmarkSatellite is the satellite name of the posts created by benchmark, so they are recognizable if cleanup fails
+const benchmarkSatellite = "SATHUB-BENCHMARK"
+
+var (
+	benchmarkSizeMB     int
+	benchmarkIterations int
+)
+
+// BenchmarkResult holds the upload throughput measured by benchmark
+type BenchmarkResult struct {
+	SizeMB     int       `json:"size_mb"`
+	Iterations int       `json:"iterations"`
+	Seconds    []float64 `json:"seconds"` // wall-clock time of each upload
+	MeanMBps   float64   `json:"mean_mbps"`
+	MinMBps    float64   `json:"min_mbps"`
+	MaxMBps    float64   `json:"max_mbps"`
+}
+
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Measure the upload throughput to the API",
+	Long: `Upload files of random data as CADU files to temporary posts and report the throughput
+in MB/s. The measurement includes TLS, multipart encoding and server processing, so it shows
+what passes will actually achieve. The posts are deleted afterwards.`,
+	Example: `  # Upload a 10 MB file 5 times
+  sathub-client benchmark --size-mb 10 --iterations 5`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBenchmark(benchmarkSizeMB, benchmarkIterations)
+	},
+}
+
+func init() {
+	benchmarkCmd.Flags().IntVar(&benchmarkSizeMB, "size-mb", 10, "Size of each uploaded file in megabytes")
+	benchmarkCmd.Flags().IntVar(&benchmarkIterations, "iterations", 5, "Number of uploads")
+}
+
+// runBenchmark uploads iterations files of sizeMB megabytes, each to its own post, and prints the throughput
+func runBenchmark(sizeMB, iterations int) error {
+	if sizeMB <= 0 || iterations <= 0 {
+		return fmt.Errorf("--size-mb and --iterations must be positive")
+	}
+	if dryRun {
+		fmt.Printf("[dry-run] Would upload %d file(s) of %d MB to temporary posts\n", iterations, sizeMB)
+		return nil
+	}
+
+	apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	path, err := writeBenchmarkFile(int64(sizeMB) << 20)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	// Delete the posts even when an upload fails halfway
+	var postIDs []string
+	defer func() {
+		for _, postID := range postIDs {
+			if err := apiClient.DeletePost(ctx, postID); err != nil {
+				logger.Warn().Err(err).Str("post_id", postID).Msg("Failed to delete benchmark post")
+			}
+		}
+	}()
+
+	result := BenchmarkResult{SizeMB: sizeMB, Iterations: iterations}
+	for i := 0; i < iterations; i++ {
+		post, err := apiClient.CreatePost(ctx, PostRequest{
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			SatelliteName: benchmarkSatellite,
+			Metadata:      `{"benchmark":true}`,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create benchmark post: %w", err)
+		}
+		postIDs = append(postIDs, post.ID)
+
+		start := time.Now()
+		if err := apiClient.UploadCADU(ctx, post.ID, path); err != nil {
+			return fmt.Errorf("upload %d failed: %w", i+1, err)
+		}
+		elapsed := time.Since(start).Seconds()
+		result.Seconds = append(result.Seconds, elapsed)
+
+		if !jsonOutput {
+			fmt.Printf("Upload %d/%d: %.2f s, %.2f MB/s\n", i+1, iterations, elapsed, float64(sizeMB)/elapsed)
+		}
+	}
+
+	var total float64
+	for i, seconds := range result.Seconds {
+		mbps := float64(sizeMB) / seconds
+		total += seconds
+		if i == 0 || mbps < result.MinMBps {
+			result.MinMBps = mbps
+		}
+		if mbps > result.MaxMBps {
+			result.MaxMBps = mbps
+		}
+	}
+	result.MeanMBps = float64(sizeMB*iterations) / total
+
+	if jsonOutput {
+		PrintJSON(result)
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Throughput: mean %.2f MB/s, min %.2f MB/s, max %.2f MB/s\n", result.MeanMBps, result.MinMBps, result.MaxMBps)
+	return nil
+}
+
+// writeBenchmarkFile writes size bytes of random data to a temporary file, random data can't be compressed on the way
+func writeBenchmarkFile(size int64) (string, error) {
+	file, err := os.CreateTemp("", "sathub-benchmark-*.cadu")
+	if err != nil {
+		return "", fmt.Errorf("failed to create benchmark file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.CopyN(file, rand.Reader, size); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write benchmark file: %w", err)
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// cleanupEveryHealthChecks is how many health check cycles pass between retention runs
+const cleanupEveryHealthChecks = 12
+
+// retentionPolicy limits what is kept in the processed directory, zero values disable a limit
+type retentionPolicy struct {
+	maxAge        time.Duration
+	maxTotalBytes int64
+}
+
+// enabled reports whether the policy would ever remove anything
+func (p retentionPolicy) enabled() bool {
+	return p.maxAge > 0 || p.maxTotalBytes > 0
+}
+
+// retentionPolicyFromConfig builds the retention policy from the cleanup config section
+func retentionPolicyFromConfig(c *config.Config) retentionPolicy {
+	return retentionPolicy{
+		maxAge:        time.Duration(c.Cleanup.MaxAgeDays) * 24 * time.Hour,
+		maxTotalBytes: int64(c.Cleanup.MaxTotalGB * 1024 * 1024 * 1024),
+	}
+}
+
+// processedEntry is a pass directory or archive in the processed directory
+type processedEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Apply the retention policy to the processed directory",
+	Long:  "Delete processed passes older than cleanup.max_age_days, then the oldest passes until the processed directory is below cleanup.max_total_gb. Use --dry-run to list what would be removed.",
+	Example: `  # Show what would be removed
+  sathub-client cleanup --dry-run`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policy := retentionPolicyFromConfig(cfg)
+		if !policy.enabled() {
+			return fmt.Errorf("no retention limits configured, set cleanup.max_age_days or cleanup.max_total_gb")
+		}
+
+		removed, err := runCleanup(cfg.PrimaryStation().Processed, policy, dryRun)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(map[string]interface{}{"dry_run": dryRun, "removed": removed})
+			return nil
+		}
+
+		if len(removed) == 0 {
+			fmt.Println("Nothing to remove")
+			return nil
+		}
+
+		var total int64
+		for _, entry := range removed {
+			total += entry.Size
+			fmt.Printf("%s  %8.1f MB  %s\n", entry.ModTime.Format("2006-01-02 15:04"), float64(entry.Size)/1024/1024, entry.Path)
+		}
+		fmt.Println()
+		if dryRun {
+			fmt.Printf("Would remove %d pass(es), %.1f MB\n", len(removed), float64(total)/1024/1024)
+		} else {
+			fmt.Printf("Removed %d pass(es), %.1f MB\n", len(removed), float64(total)/1024/1024)
+		}
+		return nil
+	},
+}
+
+// runCleanup removes the processed entries selected by the policy and returns them.
+// With dryRun set nothing is deleted.
+func runCleanup(processedDir string, policy retentionPolicy, dryRun bool) ([]processedEntry, error) {
+	entries, err := listProcessedEntries(processedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := selectExpired(entries, policy, time.Now())
+	for _, entry := range expired {
+		if !isInsideDir(entry.Path, processedDir) {
+			return nil, fmt.Errorf("refusing to delete %s outside processed directory %s", entry.Path, processedDir)
+		}
+		if dryRun {
+			logger.Info().Str("path", entry.Path).Int64("size", entry.Size).Msg("[dry-run] Would remove processed pass")
+			continue
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		removeEmptyParents(entry.Path, processedDir)
+		logger.Info().Str("path", entry.Path).Int64("size", entry.Size).Msg("Removed processed pass")
+	}
+
+	return expired, nil
+}
+
+// listProcessedEntries returns the pass directories and archives in the processed directory, oldest first
+func listProcessedEntries(processedDir string) ([]processedEntry, error) {
+	passes, err := findProcessedPasses(processedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []processedEntry
+	for _, path := range passes {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		size := info.Size()
+		if info.IsDir() {
+			size = directorySize(path)
+		}
+		entries = append(entries, processedEntry{Path: path, Size: size, ModTime: info.ModTime()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// findProcessedPasses returns the pass directories and .tar.gz archives below processedDir. Passes can be
+// nested in subdirectories by processed_naming, a directory is a pass when it contains dataset.json.
+func findProcessedPasses(processedDir string) ([]string, error) {
+	var passes []string
+	err := filepath.WalkDir(processedDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == processedDir {
+			return nil
+		}
+		if !d.IsDir() {
+			if strings.HasSuffix(d.Name(), ".tar.gz") {
+				passes = append(passes, path)
+			}
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, "dataset.json")); err == nil {
+			passes = append(passes, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed directory: %w", err)
+	}
+	return passes, nil
+}
+
+// removeEmptyParents removes the directories between path and processedDir that became empty
+func removeEmptyParents(path, processedDir string) {
+	for dir := filepath.Dir(path); isInsideDir(dir, processedDir); dir = filepath.Dir(dir) {
+		// Remove fails on directories that still have entries
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// selectExpired picks entries older than the maximum age, then the oldest remaining
+// entries until the total size is within the limit. entries must be sorted oldest first.
+func selectExpired(entries []processedEntry, policy retentionPolicy, now time.Time) []processedEntry {
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	var expired []processedEntry
+	for _, entry := range entries {
+		tooOld := policy.maxAge > 0 && now.Sub(entry.ModTime) > policy.maxAge
+		tooBig := policy.maxTotalBytes > 0 && total > policy.maxTotalBytes
+		if !tooOld && !tooBig {
+			break
+		}
+		expired = append(expired, entry)
+		total -= entry.Size
+	}
+	return expired
+}
+
+// directorySize returns the total size of the regular files below dir
+func directorySize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// isInsideDir reports whether path is strictly below dir
+func isInsideDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// compressProcessedDirectory archives dirPath to <dirPath>.tar.gz and removes the directory
+func compressProcessedDirectory(processedDir, dirPath string) (string, error) {
+	if !isInsideDir(dirPath, processedDir) {
+		return "", fmt.Errorf("refusing to compress %s outside processed directory %s", dirPath, processedDir)
+	}
+
+	archivePath := dirPath + ".tar.gz"
+	tmpPath := archivePath + ".tmp"
+	if err := writeTarGz(tmpPath, dirPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to rename archive: %w", err)
+	}
+
+	if err := os.RemoveAll(dirPath); err != nil {
+		return archivePath, fmt.Errorf("failed to remove compressed directory: %w", err)
+	}
+	return archivePath, nil
+}
+
+// writeTarGz writes the contents of dir to a gzip-compressed tarball rooted at the directory name
+func writeTarGz(archivePath, dir string) error {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	base := filepath.Dir(dir)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return file.Close()
+}
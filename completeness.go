@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// completenessCheckInterval is how often incomplete pass directories are checked again
+const completenessCheckInterval = time.Minute
+
+// trackIncomplete remembers a pass directory that isn't complete yet, inotify won't report the files added to it later
+func (fw *FileWatcher) trackIncomplete(dirPath string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.incomplete[dirPath] = true
+}
+
+// untrackIncomplete forgets an incomplete pass directory
+func (fw *FileWatcher) untrackIncomplete(dirPath string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	delete(fw.incomplete, dirPath)
+}
+
+// completenessLoop checks the incomplete pass directories every completenessCheckInterval until Stop is called
+func (fw *FileWatcher) completenessLoop() {
+	ticker := time.NewTicker(completenessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fw.stopCh:
+			return
+		case <-ticker.C:
+			fw.checkIncomplete()
+		}
+	}
+}
+
+// checkIncomplete processes the incomplete pass directories that are complete now. Directories that
+// didn't change for CompletenessTimeout are processed anyway when they have a dataset.json, without
+// one there is nothing to create a post from and they are moved to the dead-letter directory.
+func (fw *FileWatcher) checkIncomplete() {
+	fw.mu.Lock()
+	dirs := make([]string, 0, len(fw.incomplete))
+	for dirPath := range fw.incomplete {
+		dirs = append(dirs, dirPath)
+	}
+	fw.mu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dirPath := range dirs {
+		lastChange, err := lastModified(dirPath)
+		if err != nil || fw.isProcessed(dirPath) {
+			// Removed or renamed, or picked up by a scan in the meantime
+			fw.untrackIncomplete(dirPath)
+			continue
+		}
+
+		if fw.isCompleteSatellitePass(dirPath) {
+			fw.untrackIncomplete(dirPath)
+			fw.logger.Info().Str("dir", dirPath).Msg("Satellite pass directory is complete now")
+			// Errors are logged and queued for retry by the pass processing
+			fw.handleDirectoryEvent(dirPath)
+			continue
+		}
+
+		unchanged := time.Since(lastChange)
+		if unchanged < fw.config.CompletenessTimeout {
+			continue
+		}
+		fw.untrackIncomplete(dirPath)
+
+		if _, err := os.Stat(filepath.Join(dirPath, "dataset.json")); err == nil {
+			fw.logger.Warn().
+				Str("dir", dirPath).
+				Dur("unchanged_for", unchanged.Round(time.Second)).
+				Msg("Satellite pass directory never became complete, processing it anyway")
+			if !fw.waitWhilePaused(dirPath) {
+				return
+			}
+			fw.startPass(dirPath)
+			continue
+		}
+
+		fw.logger.Warn().
+			Str("dir", dirPath).
+			Dur("unchanged_for", unchanged.Round(time.Second)).
+			Msg("Satellite pass directory never became complete and has no dataset.json")
+		if fw.markProcessed(dirPath) {
+			now := time.Now()
+			reason := fmt.Sprintf("timed out: incomplete and unchanged for %s", unchanged.Round(time.Second))
+			fw.deadLetter(dirPath, &RetryEntry{
+				Path:        dirPath,
+				FailedStep:  stepTimedOut,
+				LastAttempt: now,
+				LastError:   reason,
+				History:     []RetryAttempt{{Time: now, Step: stepTimedOut, Error: reason}},
+			})
+		}
+	}
+}
+
+// lastModified returns the latest modification time of dirPath and everything in it
+func lastModified(dirPath string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dirPath {
+				return err
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// lockPollInterval is how often a locked pass directory is checked for its lock file
+const lockPollInterval = 10 * time.Second
+
+// findLockFile returns the name of the lock file SatDump keeps in dirPath while writing a pass, or ""
+func findLockFile(dirPath string) string {
+	// Also matches a plain .lock
+	matches, err := filepath.Glob(filepath.Join(dirPath, "*.lock"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+	return filepath.Base(matches[0])
+}
+
+// watchLock starts waiting in the background for the lock file of dirPath to disappear, unless that already happens
+func (fw *FileWatcher) watchLock(dirPath, lockFile string) {
+	fw.mu.Lock()
+	if fw.locked[dirPath] {
+		fw.mu.Unlock()
+		return
+	}
+	fw.locked[dirPath] = true
+	fw.mu.Unlock()
+
+	fw.logger.Info().
+		Str("dir", dirPath).
+		Str("lock_file", lockFile).
+		Dur("timeout", fw.config.CompletenessTimeout).
+		Msg("Satellite pass directory is locked by SatDump, waiting for the lock file to be removed")
+	go fw.awaitUnlock(dirPath)
+}
+
+// awaitUnlock polls dirPath every lockPollInterval until its lock file is gone and then processes the pass.
+// A lock file still there after CompletenessTimeout is considered stale and the directory is left to checkIncomplete.
+func (fw *FileWatcher) awaitUnlock(dirPath string) {
+	defer func() {
+		fw.mu.Lock()
+		delete(fw.locked, dirPath)
+		fw.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(fw.config.CompletenessTimeout)
+
+	for findLockFile(dirPath) != "" {
+		if time.Now().After(deadline) {
+			fw.logger.Warn().Str("dir", dirPath).Msg("Lock file was not removed in time, treating the satellite pass as incomplete")
+			fw.trackIncomplete(dirPath)
+			return
+		}
+		select {
+		case <-fw.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if _, err := os.Stat(dirPath); err != nil {
+		fw.logger.Debug().Str("dir", dirPath).Msg("Locked satellite pass directory was removed")
+		return
+	}
+	if !fw.isCompleteSatellitePass(dirPath) {
+		fw.logger.Warn().Str("dir", dirPath).Msg("Lock file was removed but the satellite pass is not complete, checking it again later")
+		fw.trackIncomplete(dirPath)
+		return
+	}
+
+	fw.logger.Info().Str("dir", dirPath).Msg("Lock file was removed, processing satellite pass")
+	if !fw.waitWhilePaused(dirPath) || fw.isProcessed(dirPath) {
+		return
+	}
+	fw.startPass(dirPath)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completionPostLimit is how many recent posts are offered when completing post IDs
+const completionPostLimit = 50
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate the shell completion script",
+	Long: `Print the completion script for the given shell to stdout.
+
+To load completions in the current bash session:
+  source <(sathub-client completion bash)
+
+To load them for every new session, install-service can write the bash script to
+~/.local/share/bash-completion/completions/sathub-client, or write it yourself:
+  sathub-client completion bash > ~/.local/share/bash-completion/completions/sathub-client
+  sathub-client completion zsh > "${fpath[1]}/_sathub-client"
+  sathub-client completion fish > ~/.config/fish/completions/sathub-client.fish`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// completeDirectory completes the single directory argument of a command
+func completeDirectory(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
+
+// completePostID completes the post ID argument of a command with the recent posts of the station,
+// nothing is offered when the config can't be read or the API is unreachable
+func completePostID(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	c, err := readConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	apiClient, err := newAPIClient(c, c.PrimaryStation())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	posts, err := apiClient.ListPosts(context.Background(), completionPostLimit)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var ids []string
+	for _, post := range posts {
+		if strings.HasPrefix(post.ID, toComplete) {
+			// The part after the tab is shown as a description by shells that support it
+			ids = append(ids, fmt.Sprintf("%s\t%s %s", post.ID, post.SatelliteName, post.Timestamp))
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// bashCompletionPath returns where bash-completion loads the completion script of the user from
+func bashCompletionPath(homeDir string) string {
+	return filepath.Join(homeDir, ".local", "share", "bash-completion", "completions", "sathub-client")
+}
+
+// installBashCompletion writes the bash completion script for the user
+func installBashCompletion(homeDir string) error {
+	path := bashCompletionPath(homeDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create completion directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create completion file: %w", err)
+	}
+	defer file.Close()
+	if err := rootCmd.GenBashCompletionV2(file, true); err != nil {
+		return fmt.Errorf("failed to write completion file: %w", err)
+	}
+	return nil
+}
+
+// offerBashCompletion asks whether to install the bash completion script, failures are only reported
+func offerBashCompletion(homeDir string) {
+	fmt.Print("Install bash completion for sathub-client? (Y/n): ")
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response == "n" || response == "no" {
+		return
+	}
+
+	if err := installBashCompletion(homeDir); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+	fmt.Printf("Bash completion installed to %s, it is loaded by new shells\n", bashCompletionPath(homeDir))
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,7 +17,38 @@ type Config struct {
 	LogLevel     string
 	RetryCount   int
 	RetryDelay   time.Duration
-	ProcessDelay time.Duration // Delay before processing new directories
+	ProcessDelay time.Duration // Delay before processing new directories, use GetProcessDelay once the watcher runs
+	// SatelliteAliases maps lower-case raw satellite names to their canonical form, use GetSatelliteAliases once the watcher runs
+	SatelliteAliases  map[string]string
+	IncludeSatellites []string // Glob patterns, only matching satellites are processed when non-empty
+	ExcludeSatellites []string // Glob patterns, matching satellites are skipped
+	ImageInclude      []string // File name patterns, only matching images are uploaded when non-empty
+	ImageExclude      []string // File name patterns, matching images are not uploaded
+	UploadIQ          bool     // Upload raw I/Q recordings found in the pass directory
+	IQExtensions      []string // File extensions of I/Q recordings
+	// MetadataMaxValueLen is the longest metadata string uploaded, longer ones are replaced by a placeholder
+	MetadataMaxValueLen int
+	PreUploadHook       string        // Shell command run before creating a post
+	PostUploadHook      string        // Shell command run after all uploads succeeded
+	DryRun              bool          // Log uploads and moves instead of performing them
+	DataDir             string        // Directory for local state such as upload checksums
+	MinFreeDiskMB       int64         // Free space on the watch partition below which a warning is logged
+	ShutdownTimeout     time.Duration // Time Stop waits for in-flight uploads
+	CompletenessTimeout time.Duration // Time an incomplete pass may stay unchanged before it is handled anyway
+	CompressProcessed   bool          // Archive passes as .tar.gz after moving them to ProcessedDir
+	ProcessedNaming     string        // text/template for the path of a pass below ProcessedDir
+	RecursiveDepth      int           // Directory levels below each watch path searched for passes
+	UsePolling          bool          // Poll the watch paths instead of using inotify
+	PollingInterval     time.Duration // Interval between scans when polling
+	CompletionSentinel  string        // File marking a pass directory as complete, empty to check for dataset.json and data files
+	MaxRetries          int           // Attempts for a failed pass before it is moved to DeadLetterDir
+	DeadLetterDir       string        // Directory for passes that failed MaxRetries times
+	ValidateImages      bool          // Decode PNG images before upload and skip corrupt ones
+	HistoryDB           string        // SQLite pass history, shared by all stations
+	StationID           string        // Identifies the station in logs when running several stations
+
+	// mu protects ProcessDelay and SatelliteAliases, which may change while passes are processed
+	mu sync.RWMutex
 }
 
 // LoadConfig loads configuration from environment variables (legacy support)
@@ -72,12 +104,38 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// GetProcessDelay returns the delay before processing new directories
+func (c *Config) GetProcessDelay() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ProcessDelay
+}
+
+// SetProcessDelay changes the delay before processing new directories, safe while the watcher runs
+func (c *Config) SetProcessDelay(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ProcessDelay = d
+}
+
+// GetSatelliteAliases returns the satellite name aliases
+func (c *Config) GetSatelliteAliases() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.SatelliteAliases
+}
+
+// SetSatelliteAliases replaces the satellite name aliases, safe while the watcher runs
+func (c *Config) SetSatelliteAliases(aliases map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SatelliteAliases = aliases
+}
+
 // UpdateFromServerSettings updates the config with settings received from the server
-func (c *Config) UpdateFromServerSettings(settings map[string]interface{}) {
-	if processDelay, ok := settings["process_delay"]; ok {
-		if delay, ok := processDelay.(float64); ok {
-			c.ProcessDelay = time.Duration(delay) * time.Second
-		}
+func (c *Config) UpdateFromServerSettings(settings ServerSettings) {
+	if settings.ProcessDelay > 0 {
+		c.SetProcessDelay(time.Duration(settings.ProcessDelay) * time.Second)
 	}
 	// Add more settings here as they are added to the server
 }
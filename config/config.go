@@ -1,48 +1,232 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the client configuration
 type Config struct {
-	Station   StationConfig   `yaml:"station"`
-	Paths     PathsConfig     `yaml:"paths"`
-	Intervals IntervalsConfig `yaml:"intervals"`
-	Options   OptionsConfig   `yaml:"options"`
+	Station    StationConfig    `yaml:"station" toml:"station" json:"station"`
+	Stations   []StationConfig  `yaml:"stations,omitempty" toml:"stations,omitempty" json:"stations,omitempty"` // one watcher per entry, replaces station
+	Paths      PathsConfig      `yaml:"paths" toml:"paths" json:"paths"`
+	Intervals  IntervalsConfig  `yaml:"intervals" toml:"intervals" json:"intervals"`
+	Options    OptionsConfig    `yaml:"options" toml:"options" json:"options"`
+	Satellites SatellitesConfig `yaml:"satellites" toml:"satellites" json:"satellites"`
+	Filters    FiltersConfig    `yaml:"filters" toml:"filters" json:"filters"`
+	Hooks      HooksConfig      `yaml:"hooks" toml:"hooks" json:"hooks"`
+	Cleanup    CleanupConfig    `yaml:"cleanup" toml:"cleanup" json:"cleanup"`
+
+	format string // File format the config was loaded from, see Format*
+}
+
+// Config file formats
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// Log formats for the log_format option, json and json-lines both write one JSON object per line
+const (
+	LogFormatConsole   = "console"
+	LogFormatJSON      = "json"
+	LogFormatJSONLines = "json-lines"
+)
+
+// JSONLogs reports whether log lines are written as JSON instead of for humans
+func (o OptionsConfig) JSONLogs() bool {
+	return o.LogFormat == LogFormatJSON || o.LogFormat == LogFormatJSONLines
+}
+
+// FormatForPath returns the config file format for path based on its extension, defaulting to YAML
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatYAML
+	}
+}
+
+// Format returns the file format the config was loaded from, empty for configs not loaded from a file
+func (c *Config) Format() string {
+	return c.format
+}
+
+// SetFormat sets the file format used by Save
+func (c *Config) SetFormat(format string) {
+	c.format = format
 }
 
 // StationConfig holds station-specific configuration
 type StationConfig struct {
-	Token  string `yaml:"token"`
-	APIURL string `yaml:"api_url"`
+	Token  string `yaml:"token" toml:"token" json:"token"`
+	APIURL string `yaml:"api_url" toml:"api_url" json:"api_url"`
+	// ID identifies the station in logs, filled in from the health check response
+	ID string `yaml:"station_id,omitempty" toml:"station_id,omitempty" json:"station_id,omitempty"`
+	// Paths of an entry of stations, empty values use the paths section
+	Watch     string `yaml:"watch,omitempty" toml:"watch,omitempty" json:"watch,omitempty"`
+	Processed string `yaml:"processed,omitempty" toml:"processed,omitempty" json:"processed,omitempty"`
 }
 
 // PathsConfig holds directory paths
 type PathsConfig struct {
-	Watch     string `yaml:"watch"`
-	Processed string `yaml:"processed"`
+	Watch     string `yaml:"watch" toml:"watch" json:"watch"`
+	Processed string `yaml:"processed" toml:"processed" json:"processed"`
+	// DeadLetter receives passes that failed options.max_retries times
+	DeadLetter string `yaml:"dead_letter" toml:"dead_letter" json:"dead_letter"`
+	// ProcessedNaming is a text/template for the path of a pass below processed, e.g. {{.Satellite}}/{{.Name}}
+	ProcessedNaming string `yaml:"processed_naming,omitempty" toml:"processed_naming,omitempty" json:"processed_naming,omitempty"`
 }
 
 // IntervalsConfig holds timing configurations
 type IntervalsConfig struct {
-	HealthCheck  int `yaml:"health_check"`  // seconds
-	ProcessDelay int `yaml:"process_delay"` // seconds
+	HealthCheck  int `yaml:"health_check" toml:"health_check" json:"health_check"`    // seconds
+	ProcessDelay int `yaml:"process_delay" toml:"process_delay" json:"process_delay"` // seconds
+	// ShutdownTimeout is how long to wait for in-flight uploads on shutdown, in seconds
+	ShutdownTimeout int `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout"`
+	// StatusUpdate is the interval between status updates sent to the server over the WebSocket, in seconds
+	StatusUpdate int `yaml:"status_update_interval" toml:"status_update_interval" json:"status_update_interval"`
+	// CompletenessTimeout is how long an incomplete pass directory may stay unchanged before it is processed anyway
+	// or moved to the dead-letter directory, in minutes
+	CompletenessTimeout int `yaml:"completeness_timeout_minutes" toml:"completeness_timeout_minutes" json:"completeness_timeout_minutes"`
 }
 
 // OptionsConfig holds optional settings
 type OptionsConfig struct {
-	Insecure bool `yaml:"insecure"`
-	Verbose  bool `yaml:"verbose"`
+	Insecure     bool   `yaml:"insecure" toml:"insecure" json:"insecure"`
+	Verbose      bool   `yaml:"verbose" toml:"verbose" json:"verbose"`
+	MetricsAddr  string `yaml:"metrics_addr" toml:"metrics_addr" json:"metrics_addr"`          // e.g. ":9090", empty disables the metrics server
+	CheckUpdates bool   `yaml:"check_updates" toml:"check_updates" json:"check_updates"`       // log at startup when a newer version is available
+	LogFile      string `yaml:"log_file" toml:"log_file" json:"log_file"`                      // empty disables file logging
+	LogMaxSizeMB int    `yaml:"log_max_size_mb" toml:"log_max_size_mb" json:"log_max_size_mb"` // rotate log file at startup above this size
+	// LogFormat is the format of the log lines written to stdout, see LogFormat*
+	LogFormat string `yaml:"log_format" toml:"log_format" json:"log_format"`
+	// MaxUploadBytesPerSecond limits the speed of each upload, 0 for unlimited
+	MaxUploadBytesPerSecond int64 `yaml:"max_upload_bytes_per_second" toml:"max_upload_bytes_per_second" json:"max_upload_bytes_per_second"`
+	// MinFreeDiskMB warns below this free space on the watch partition, uploads are skipped below half of it
+	MinFreeDiskMB int64 `yaml:"min_free_disk_mb" toml:"min_free_disk_mb" json:"min_free_disk_mb"`
+	// ValidateImages decodes PNG images before uploading them and skips corrupt ones
+	ValidateImages bool `yaml:"validate_images" toml:"validate_images" json:"validate_images"`
+	// ValidateCBOR decodes CBOR files before uploading them and skips corrupt ones, nil means enabled
+	ValidateCBOR *bool `yaml:"validate_cbor,omitempty" toml:"validate_cbor,omitempty" json:"validate_cbor,omitempty"`
+	// MaxRetries is how many times a failed pass is attempted before it is given up
+	MaxRetries int `yaml:"max_retries" toml:"max_retries" json:"max_retries"`
+	// WSMaxReconnectAttempts is how many WebSocket reconnects in a row may fail before giving up on it, 0 for no limit
+	WSMaxReconnectAttempts int `yaml:"ws_max_reconnect_attempts,omitempty" toml:"ws_max_reconnect_attempts,omitempty" json:"ws_max_reconnect_attempts,omitempty"`
+	// PIDFile holds the PID of the running client so a second instance refuses to start
+	PIDFile string `yaml:"pid_file" toml:"pid_file" json:"pid_file"`
+	// RecursiveDepth is how many directory levels below the watch path are searched for passes
+	RecursiveDepth int `yaml:"recursive_depth" toml:"recursive_depth" json:"recursive_depth"`
+	// MetadataMaxValueLen is the length in bytes above which dataset.json strings are replaced by a placeholder
+	MetadataMaxValueLen int `yaml:"metadata_max_value_len" toml:"metadata_max_value_len" json:"metadata_max_value_len"`
+	// UsePolling lists the watch directories every PollingIntervalSec instead of relying on inotify,
+	// which doesn't see changes on NFS and CIFS mounts
+	UsePolling         bool `yaml:"use_polling" toml:"use_polling" json:"use_polling"`
+	PollingIntervalSec int  `yaml:"polling_interval_sec" toml:"polling_interval_sec" json:"polling_interval_sec"`
+	// CompletionSentinel is a file the pipeline writes into a pass directory when it is done, empty to detect
+	// complete passes by their dataset.json and CADU or CBOR files
+	CompletionSentinel string `yaml:"completion_sentinel,omitempty" toml:"completion_sentinel,omitempty" json:"completion_sentinel,omitempty"`
+	// Request timeouts, zero values use the defaults
+	ConnectTimeoutSec     int     `yaml:"connect_timeout_sec,omitempty" toml:"connect_timeout_sec,omitempty" json:"connect_timeout_sec,omitempty"`
+	HealthCheckTimeoutSec int     `yaml:"health_check_timeout_sec,omitempty" toml:"health_check_timeout_sec,omitempty" json:"health_check_timeout_sec,omitempty"`
+	UploadTimeoutPerMBSec float64 `yaml:"upload_timeout_per_mb_sec,omitempty" toml:"upload_timeout_per_mb_sec,omitempty" json:"upload_timeout_per_mb_sec,omitempty"`
+	// TLS files for private CAs and mutual TLS, independent of Insecure
+	TLSCACert     string `yaml:"tls_ca_cert,omitempty" toml:"tls_ca_cert,omitempty" json:"tls_ca_cert,omitempty"`
+	TLSClientCert string `yaml:"tls_client_cert,omitempty" toml:"tls_client_cert,omitempty" json:"tls_client_cert,omitempty"`
+	TLSClientKey  string `yaml:"tls_client_key,omitempty" toml:"tls_client_key,omitempty" json:"tls_client_key,omitempty"`
+	// ValidateAPIReachability dials every API host on startup and refuses to start when one isn't listening
+	ValidateAPIReachability bool `yaml:"validate_api_reachability,omitempty" toml:"validate_api_reachability,omitempty" json:"validate_api_reachability,omitempty"`
+}
+
+// SatellitesConfig holds satellite name handling
+type SatellitesConfig struct {
+	// Aliases maps raw satellite names (case-insensitive) to their canonical form
+	Aliases map[string]string `yaml:"aliases,omitempty" toml:"aliases,omitempty" json:"aliases,omitempty"`
+}
+
+// FiltersConfig holds satellite allow and block lists (case-insensitive glob patterns)
+type FiltersConfig struct {
+	Include []string `yaml:"include,omitempty" toml:"include,omitempty" json:"include,omitempty"` // only process matching satellites when non-empty
+	Exclude []string `yaml:"exclude,omitempty" toml:"exclude,omitempty" json:"exclude,omitempty"` // skip matching satellites
+	// Image file name patterns (filepath.Match, case-insensitive), include is applied before exclude
+	ImageInclude []string `yaml:"image_include_patterns,omitempty" toml:"image_include_patterns,omitempty" json:"image_include_patterns,omitempty"`
+	ImageExclude []string `yaml:"image_exclude_patterns,omitempty" toml:"image_exclude_patterns,omitempty" json:"image_exclude_patterns,omitempty"`
+	// Raw I/Q recordings in the pass directory are uploaded when enabled, they can be several gigabytes
+	UploadIQ     bool     `yaml:"upload_iq,omitempty" toml:"upload_iq,omitempty" json:"upload_iq,omitempty"`
+	IQExtensions []string `yaml:"iq_extensions,omitempty" toml:"iq_extensions,omitempty" json:"iq_extensions,omitempty"` // file extensions of I/Q recordings
 }
 
-// Load reads the configuration from a YAML file
+// HooksConfig holds shell commands run around each upload
+type HooksConfig struct {
+	PreUpload  string `yaml:"pre_upload,omitempty" toml:"pre_upload,omitempty" json:"pre_upload,omitempty"`    // non-zero exit aborts the upload
+	PostUpload string `yaml:"post_upload,omitempty" toml:"post_upload,omitempty" json:"post_upload,omitempty"` // failures are only logged
+}
+
+// CleanupConfig holds retention settings for the processed directory, zero values disable a limit
+type CleanupConfig struct {
+	MaxAgeDays int     `yaml:"max_age_days,omitempty" toml:"max_age_days,omitempty" json:"max_age_days,omitempty"` // delete processed passes older than this
+	MaxTotalGB float64 `yaml:"max_total_gb,omitempty" toml:"max_total_gb,omitempty" json:"max_total_gb,omitempty"` // delete oldest processed passes above this total size
+	Compress   bool    `yaml:"compress,omitempty" toml:"compress,omitempty" json:"compress,omitempty"`             // store processed passes as <dirname>.tar.gz
+}
+
+// Load reads the configuration from a YAML file, or a TOML or JSON file when path ends in .toml or .json
 func Load(path string) (*Config, error) {
+	return load(path, FormatForPath(path))
+}
+
+// LoadTOML reads the configuration from a TOML file
+func LoadTOML(path string) (*Config, error) {
+	return load(path, FormatTOML)
+}
+
+// LoadJSON reads the configuration from a JSON file
+func LoadJSON(path string) (*Config, error) {
+	return load(path, FormatJSON)
+}
+
+// load reads the configuration from path in the given format, applies the environment overrides and validates it
+func load(path, format string) (*Config, error) {
+	config, err := read(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	ApplyEnvironmentOverrides(config)
+
+	// Validate required fields
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Read reads the configuration file at path like Load, without applying the environment overrides
+// or validating it, for editing a config file without writing values from the environment to it
+func Read(path string) (*Config, error) {
+	return read(path, FormatForPath(path))
+}
+
+// read parses the configuration file at path in the given format
+func read(path, format string) (*Config, error) {
 	// Expand tilde in path
 	path = expandPath(path)
 
@@ -52,21 +236,42 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse YAML
+	// Parse file
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	switch format {
+	case FormatTOML:
+		err = toml.Unmarshal(data, &config)
+	case FormatJSON:
+		err = unmarshalJSON(data, &config)
+	default:
+		err = yaml.Unmarshal(data, &config)
 	}
-
-	// Validate required fields
-	if err := config.Validate(); err != nil {
-		return nil, err
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	config.format = format
 
 	return &config, nil
 }
 
-// Save writes the configuration to a YAML file
+// unmarshalJSON decodes a JSON config, also accepting the {"ok": true, "data": {...}}
+// envelope printed by config show --json so its output can be used as a config file
+func unmarshalJSON(data []byte, config *Config) error {
+	var envelope struct {
+		OK   *bool           `json:"ok"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+	if envelope.OK != nil && len(envelope.Data) > 0 {
+		data = envelope.Data
+	}
+	return json.Unmarshal(data, config)
+}
+
+// Save writes the configuration in the format it was loaded from, or the one matching
+// the file extension for configs that were not loaded from a file
 func (c *Config) Save(path string) error {
 	// Expand tilde in path
 	path = expandPath(path)
@@ -77,8 +282,24 @@ func (c *Config) Save(path string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(c)
+	format := c.format
+	if format == "" {
+		format = FormatForPath(path)
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case FormatTOML:
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(c)
+		data = buf.Bytes()
+	case FormatJSON:
+		data, err = json.MarshalIndent(c, "", "  ")
+		data = append(data, '\n')
+	default:
+		data, err = yaml.Marshal(c)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -93,11 +314,18 @@ func (c *Config) Save(path string) error {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.Station.Token == "" {
-		return fmt.Errorf("station token is required")
+	if len(c.Stations) == 0 {
+		if c.Station.Token == "" {
+			return fmt.Errorf("station token is required")
+		}
+		if c.Station.APIURL == "" {
+			return fmt.Errorf("api_url is required")
+		}
 	}
-	if c.Station.APIURL == "" {
-		return fmt.Errorf("api_url is required")
+	for i, station := range c.Stations {
+		if station.Token == "" {
+			return fmt.Errorf("stations[%d]: token is required", i)
+		}
 	}
 	if c.Paths.Watch == "" {
 		return fmt.Errorf("watch path is required")
@@ -111,33 +339,347 @@ func (c *Config) Validate() error {
 	if c.Intervals.ProcessDelay <= 0 {
 		return fmt.Errorf("process_delay must be positive")
 	}
+	switch c.Options.LogFormat {
+	case "", LogFormatConsole, LogFormatJSON, LogFormatJSONLines:
+	default:
+		return fmt.Errorf("log_format must be %s, %s or %s", LogFormatConsole, LogFormatJSON, LogFormatJSONLines)
+	}
+	if c.Options.RecursiveDepth < 0 || c.Options.RecursiveDepth > MaxRecursiveDepth {
+		return fmt.Errorf("recursive_depth must be between 1 and %d", MaxRecursiveDepth)
+	}
+	if c.Paths.ProcessedNaming != "" {
+		if _, err := template.New("processed_naming").Parse(c.Paths.ProcessedNaming); err != nil {
+			return fmt.Errorf("invalid processed_naming template: %w", err)
+		}
+	}
+	if sentinel := c.Options.CompletionSentinel; sentinel != "" && (filepath.Base(sentinel) != sentinel || sentinel == "." || sentinel == "..") {
+		return fmt.Errorf("completion_sentinel must be a file name, not a path")
+	}
+
+	// Two stations watching the same directory would upload every pass twice
+	watchedBy := make(map[string]int)
+	for i, station := range c.StationConfigs() {
+		if _, err := parseAPIURL(station.APIURL); err != nil {
+			if len(c.Stations) > 0 {
+				return fmt.Errorf("stations[%d]: %w", i, err)
+			}
+			return err
+		}
+		watch := filepath.Clean(expandPath(station.Watch))
+		if other, ok := watchedBy[watch]; ok {
+			return fmt.Errorf("stations[%d]: watch path %s is already used by stations[%d]", i, station.Watch, other)
+		}
+		watchedBy[watch] = i
+	}
+	return nil
+}
+
+// MinTokenLength is the length of the station tokens issued by SatHub, shorter ones are probably truncated
+const MinTokenLength = 32
+
+// ValidationWarning is a problem found by ValidateRuntime that doesn't prevent the client from starting
+type ValidationWarning struct {
+	Field   string
+	Message string
+}
+
+// String returns the warning as "field: message"
+func (w ValidationWarning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// ValidateRuntime checks the configuration against the system it runs on: whether the directories exist and
+// are usable, and whether tokens look right. Unlike Validate it only returns warnings, a watch
+// directory may be created later by SatDump and a processed directory is created on the first pass.
+func (c *Config) ValidateRuntime() []ValidationWarning {
+	var warnings []ValidationWarning
+	warn := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, ValidationWarning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, station := range c.StationConfigs() {
+		stationField, watchField, processedField := "station", "paths.watch", "paths.processed"
+		if len(c.Stations) > 0 {
+			stationField = fmt.Sprintf("stations[%d]", i)
+			watchField = stationField + ".watch"
+			processedField = stationField + ".processed"
+		}
+
+		if station.Token != "" && len(station.Token) < MinTokenLength {
+			warn(stationField+".token", "token is only %d characters long, expected at least %d", len(station.Token), MinTokenLength)
+		}
+
+		if err := checkReadableDir(expandPath(station.Watch)); err != nil {
+			warn(watchField, "%v", err)
+		}
+		if err := checkCreatableDir(expandPath(station.Processed)); err != nil {
+			warn(processedField, "%v", err)
+		}
+	}
+	return warnings
+}
+
+// checkReadableDir returns an error when dir doesn't exist, isn't a directory or can't be listed
+func checkReadableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("directory %s does not exist", dir)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("directory %s is not readable: %w", dir, err)
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("directory %s is not readable: %w", dir, err)
+	}
+	return nil
+}
+
+// checkCreatableDir returns an error when dir isn't a writable directory and can't be created as one
+func checkCreatableDir(dir string) error {
+	// The nearest existing ancestor is where the missing directories would be created
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("directory %s can't be created", dir)
+		}
+		existing = parent
+	}
+
+	f, err := os.CreateTemp(existing, ".sathub-write-check-*")
+	if err != nil {
+		if existing == dir {
+			return fmt.Errorf("directory %s is not writable: %w", dir, err)
+		}
+		return fmt.Errorf("directory %s can't be created in %s: %w", dir, existing, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
 	return nil
 }
 
+// apiDialTimeout is how long CheckAPIReachability waits for a TCP connection to an API host
+const apiDialTimeout = 5 * time.Second
+
+// parseAPIURL parses an api_url and rejects schemes other than http and https and URLs without a host
+func parseAPIURL(apiURL string) (*url.URL, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("api_url %q is malformed: %w", apiURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("api_url %q has unsupported scheme %q, expected http or https", apiURL, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("api_url %q has no host", apiURL)
+	}
+	return u, nil
+}
+
+// CheckAPIReachability opens a TCP connection to the API host of every station to find typos and
+// wrong ports before the client starts. The connection is closed right away, nothing is sent.
+func (c *Config) CheckAPIReachability() error {
+	checked := make(map[string]bool)
+	for _, station := range c.StationConfigs() {
+		u, err := parseAPIURL(station.APIURL)
+		if err != nil {
+			return err
+		}
+
+		port := u.Port()
+		if port == "" {
+			port = "443"
+			if u.Scheme == "http" {
+				port = "80"
+			}
+		}
+		addr := net.JoinHostPort(u.Hostname(), port)
+		if checked[addr] {
+			continue
+		}
+		checked[addr] = true
+
+		conn, err := net.DialTimeout("tcp", addr, apiDialTimeout)
+		if err != nil {
+			return fmt.Errorf("api_url %q is unreachable: %w", station.APIURL, err)
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+// StationConfigs returns the entries of stations, or the single station when stations is not set.
+// Empty API URLs and paths are filled in from the station and paths sections.
+func (c *Config) StationConfigs() []StationConfig {
+	stations := c.Stations
+	if len(stations) == 0 {
+		stations = []StationConfig{c.Station}
+	}
+
+	resolved := make([]StationConfig, len(stations))
+	for i, station := range stations {
+		if station.APIURL == "" {
+			station.APIURL = c.Station.APIURL
+		}
+		if station.APIURL == "" {
+			station.APIURL = DefaultAPIURL
+		}
+		if station.Watch == "" {
+			station.Watch = c.Paths.Watch
+		}
+		if station.Processed == "" {
+			station.Processed = c.Paths.Processed
+		}
+		resolved[i] = station
+	}
+	return resolved
+}
+
+// PrimaryStation returns the station used by one-off commands such as scan and upload, the first entry of stations
+func (c *Config) PrimaryStation() StationConfig {
+	return c.StationConfigs()[0]
+}
+
 // Default returns a configuration with default values
 func Default() *Config {
-	homeDir, _ := os.UserHomeDir()
-
 	return &Config{
 		Station: StationConfig{
 			Token:  "",
 			APIURL: DefaultAPIURL,
 		},
 		Paths: PathsConfig{
-			Watch:     filepath.Join(homeDir, "sathub", "data"),
-			Processed: filepath.Join(homeDir, "sathub", "processed"),
+			Watch:           expandPath(filepath.Join(DefaultDataDir, "data")),
+			Processed:       expandPath(filepath.Join(DefaultDataDir, "processed")),
+			DeadLetter:      expandPath(DefaultDeadLetterDir),
+			ProcessedNaming: DefaultProcessedNaming,
 		},
 		Intervals: IntervalsConfig{
-			HealthCheck:  DefaultHealthCheckInterval,
-			ProcessDelay: DefaultProcessDelay,
+			HealthCheck:         DefaultHealthCheckInterval,
+			ProcessDelay:        DefaultProcessDelay,
+			ShutdownTimeout:     DefaultShutdownTimeout,
+			StatusUpdate:        DefaultStatusUpdateInterval,
+			CompletenessTimeout: DefaultCompletenessTimeout,
+		},
+		Filters: FiltersConfig{
+			ImageInclude: DefaultImageIncludePatterns,
+			IQExtensions: DefaultIQExtensions,
 		},
 		Options: OptionsConfig{
-			Insecure: false,
-			Verbose:  false,
+			Insecure:            false,
+			Verbose:             false,
+			LogMaxSizeMB:        DefaultLogMaxSizeMB,
+			LogFormat:           LogFormatConsole,
+			MinFreeDiskMB:       DefaultMinFreeDiskMB,
+			MaxRetries:          DefaultMaxRetries,
+			PIDFile:             DefaultPIDFile,
+			ValidateCBOR:        boolPtr(true),
+			RecursiveDepth:      DefaultRecursiveDepth,
+			MetadataMaxValueLen: DefaultMetadataMaxValueLen,
+			PollingIntervalSec:  DefaultPollingInterval,
 		},
 	}
 }
 
+// FillDefaults sets optional fields left at zero, e.g. by older config files, to the values the client uses for them
+func (c *Config) FillDefaults() {
+	if c.Paths.DeadLetter == "" {
+		c.Paths.DeadLetter = DefaultDeadLetterDir
+	}
+	if c.Paths.ProcessedNaming == "" {
+		c.Paths.ProcessedNaming = DefaultProcessedNaming
+	}
+	if c.Intervals.ShutdownTimeout <= 0 {
+		c.Intervals.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.Intervals.StatusUpdate <= 0 {
+		c.Intervals.StatusUpdate = DefaultStatusUpdateInterval
+	}
+	if c.Intervals.CompletenessTimeout <= 0 {
+		c.Intervals.CompletenessTimeout = DefaultCompletenessTimeout
+	}
+	if c.Options.LogMaxSizeMB <= 0 {
+		c.Options.LogMaxSizeMB = DefaultLogMaxSizeMB
+	}
+	if c.Options.LogFormat == "" {
+		c.Options.LogFormat = LogFormatConsole
+	}
+	if c.Options.MinFreeDiskMB <= 0 {
+		c.Options.MinFreeDiskMB = DefaultMinFreeDiskMB
+	}
+	if c.Options.MaxRetries <= 0 {
+		c.Options.MaxRetries = DefaultMaxRetries
+	}
+	if c.Options.PIDFile == "" {
+		c.Options.PIDFile = DefaultPIDFile
+	}
+	if c.Options.RecursiveDepth <= 0 {
+		c.Options.RecursiveDepth = DefaultRecursiveDepth
+	}
+	if c.Options.MetadataMaxValueLen <= 0 {
+		c.Options.MetadataMaxValueLen = DefaultMetadataMaxValueLen
+	}
+	if c.Options.PollingIntervalSec <= 0 {
+		c.Options.PollingIntervalSec = DefaultPollingInterval
+	}
+}
+
+// CBORValidationEnabled reports whether CBOR files are checked before upload, the default when validate_cbor is not set
+func (o OptionsConfig) CBORValidationEnabled() bool {
+	return o.ValidateCBOR == nil || *o.ValidateCBOR
+}
+
+// boolPtr returns a pointer to b, for optional settings that default to true
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// SatelliteAliases returns the default aliases overlaid with the configured ones, keyed by lower-case name
+func (c *Config) SatelliteAliases() map[string]string {
+	aliases := make(map[string]string, len(DefaultAliases)+len(c.Satellites.Aliases))
+	for raw, canonical := range DefaultAliases {
+		aliases[strings.ToLower(raw)] = canonical
+	}
+	for raw, canonical := range c.Satellites.Aliases {
+		aliases[strings.ToLower(strings.TrimSpace(raw))] = canonical
+	}
+	return aliases
+}
+
+// xdgDataHome returns $XDG_DATA_HOME, or ~/.local/share when it is unset or not absolute as the spec requires
+func xdgDataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	return "~/.local/share"
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, or ~/.config when it is unset or not absolute as the spec requires
+func xdgConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	return "~/.config"
+}
+
 // expandPath expands ~ to home directory
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
@@ -150,14 +692,15 @@ func expandPath(path string) string {
 	return path
 }
 
-// LoadOrDefault loads config from path, or returns default if file doesn't exist
-func LoadOrDefault(path string) (*Config, error) {
+// ReadOrDefault reads the config file at path without the environment overrides, or creates it with
+// the defaults if it doesn't exist. found reports whether the file existed.
+func ReadOrDefault(path string) (config *Config, found bool, err error) {
 	expandedPath := expandPath(path)
 
 	// Check if file exists
 	if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
 		// Return default config
-		config := Default()
+		config = Default()
 
 		// Try to save it for next time
 		if err := config.Save(expandedPath); err != nil {
@@ -168,11 +711,88 @@ func LoadOrDefault(path string) (*Config, error) {
 			fmt.Fprintf(os.Stderr, "Please edit this file and set your station token.\n")
 		}
 
-		return config, nil
+		return config, false, nil
+	}
+
+	// Read existing config
+	config, err = Read(path)
+	return config, true, err
+}
+
+// FromEnvironment builds a configuration from the defaults and SATHUB_* environment variables only
+func FromEnvironment() (*Config, error) {
+	config := Default()
+	ApplyEnvironmentOverrides(config)
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
+	return config, nil
+}
+
+// environmentOverrides lists the SATHUB_* environment variables and the config field each one overrides
+var environmentOverrides = []struct {
+	name  string
+	field func(c *Config) interface{}
+}{
+	{"SATHUB_TOKEN", func(c *Config) interface{} { return &c.Station.Token }},
+	{"SATHUB_API_URL", func(c *Config) interface{} { return &c.Station.APIURL }},
+	{"SATHUB_WATCH", func(c *Config) interface{} { return &c.Paths.Watch }},
+	{"SATHUB_PROCESSED", func(c *Config) interface{} { return &c.Paths.Processed }},
+	{"SATHUB_HEALTH_CHECK", func(c *Config) interface{} { return &c.Intervals.HealthCheck }},
+	{"SATHUB_PROCESS_DELAY", func(c *Config) interface{} { return &c.Intervals.ProcessDelay }},
+	{"SATHUB_INSECURE", func(c *Config) interface{} { return &c.Options.Insecure }},
+	{"SATHUB_VERBOSE", func(c *Config) interface{} { return &c.Options.Verbose }},
+}
 
-	// Load existing config
-	return Load(path)
+// ApplyEnvironmentOverrides overwrites config fields with the SATHUB_* environment variables that are set
+func ApplyEnvironmentOverrides(c *Config) {
+	for _, override := range environmentOverrides {
+		value, ok := os.LookupEnv(override.name)
+		if !ok {
+			continue
+		}
+		switch field := override.field(c).(type) {
+		case *string:
+			*field = value
+		case *int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring %s=%q, not a number\n", override.name, value)
+				continue
+			}
+			*field = n
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring %s=%q, not a boolean\n", override.name, value)
+				continue
+			}
+			*field = b
+		}
+	}
+}
+
+// RestoreEnvironmentOverrides sets the fields overridden by SATHUB_* environment variables back to
+// their values in file, so a config is saved without values that only come from the environment
+func RestoreEnvironmentOverrides(c, file *Config) {
+	for _, override := range environmentOverrides {
+		if _, ok := os.LookupEnv(override.name); !ok {
+			continue
+		}
+		switch field := override.field(c).(type) {
+		case *string:
+			*field = *override.field(file).(*string)
+		case *int:
+			*field = *override.field(file).(*int)
+		case *bool:
+			*field = *override.field(file).(*bool)
+		}
+	}
+}
+
+// ExpandPath expands ~ to the user's home directory
+func ExpandPath(path string) string {
+	return expandPath(path)
 }
 
 // GetConfigPath returns the expanded config path
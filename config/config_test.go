@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConfig returns a valid config that sets the fields easily lost in a round trip
+func testConfig(dir string) *Config {
+	c := Default()
+	c.Stations = []StationConfig{
+		{Token: "token-one", APIURL: "https://api.sathub.de", Watch: filepath.Join(dir, "one"), Processed: filepath.Join(dir, "one-processed")},
+		{Token: "token-two", APIURL: "https://sathub.example.com:8443/api", ID: "station-2", Watch: filepath.Join(dir, "two")},
+	}
+	c.Paths.Watch = filepath.Join(dir, "watch")
+	c.Paths.Processed = filepath.Join(dir, "processed")
+	c.Satellites.Aliases = map[string]string{
+		"NOAA19":      "NOAA 19",
+		"METEOR-M2 3": "METEOR-M2-3",
+	}
+	c.Options.ValidateCBOR = boolPtr(false)
+	c.Intervals.ProcessDelay = 42
+	return c
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		ext    string
+		load   func(path string) (*Config, error)
+		marker string // Only found in a file of this format
+	}{
+		{FormatYAML, ".yaml", Load, "stations:\n"},
+		{FormatTOML, ".toml", LoadTOML, "[[stations]]\n"},
+		{FormatJSON, ".json", LoadJSON, `"stations": [`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := t.TempDir()
+			want := testConfig(dir)
+
+			path := filepath.Join(dir, "config"+tt.ext)
+			if err := want.Save(path); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if loaded.Format() != tt.format {
+				t.Errorf("Format() = %q, want %q", loaded.Format(), tt.format)
+			}
+			assertConfigEqual(t, loaded, want)
+
+			// Saving under a name without a known extension keeps the format the config was loaded from
+			loaded.Intervals.ProcessDelay = 7
+			want.Intervals.ProcessDelay = 7
+			resaved := filepath.Join(dir, "config.conf")
+			if err := loaded.Save(resaved); err != nil {
+				t.Fatalf("second Save failed: %v", err)
+			}
+			data, err := os.ReadFile(resaved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.marker) {
+				t.Errorf("saved file is not %s:\n%s", tt.format, data)
+			}
+
+			reloaded, err := tt.load(resaved)
+			if err != nil {
+				t.Fatalf("reloading failed: %v", err)
+			}
+			assertConfigEqual(t, reloaded, want)
+		})
+	}
+}
+
+// assertConfigEqual compares the fields set by testConfig
+func assertConfigEqual(t *testing.T, got, want *Config) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got.Stations, want.Stations) {
+		t.Errorf("stations = %+v, want %+v", got.Stations, want.Stations)
+	}
+	if !reflect.DeepEqual(got.Satellites.Aliases, want.Satellites.Aliases) {
+		t.Errorf("satellite aliases = %v, want %v", got.Satellites.Aliases, want.Satellites.Aliases)
+	}
+	if got.Options.ValidateCBOR == nil {
+		t.Errorf("validate_cbor was lost")
+	} else if *got.Options.ValidateCBOR != *want.Options.ValidateCBOR {
+		t.Errorf("validate_cbor = %v, want %v", *got.Options.ValidateCBOR, *want.Options.ValidateCBOR)
+	}
+	if got.Paths != want.Paths {
+		t.Errorf("paths = %+v, want %+v", got.Paths, want.Paths)
+	}
+	if got.Intervals != want.Intervals {
+		t.Errorf("intervals = %+v, want %+v", got.Intervals, want.Intervals)
+	}
+}
+
+func TestEnvironmentOverridesAreNotSaved(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	file := testConfig(dir)
+	file.Station.Token = "file-token"
+	if err := file.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SATHUB_TOKEN", "environment-token")
+	t.Setenv("SATHUB_PROCESS_DELAY", "99")
+
+	read, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if read.Station.Token != "file-token" {
+		t.Errorf("Read token = %q, want the token from the file", read.Station.Token)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Station.Token != "environment-token" || loaded.Intervals.ProcessDelay != 99 {
+		t.Fatalf("Load didn't apply the environment: token %q, process delay %d", loaded.Station.Token, loaded.Intervals.ProcessDelay)
+	}
+
+	// A setting changed at runtime is saved, the environment values are not
+	loaded.Paths.Watch = filepath.Join(dir, "elsewhere")
+	RestoreEnvironmentOverrides(loaded, read)
+	if err := loaded.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Station.Token != "file-token" || saved.Intervals.ProcessDelay != file.Intervals.ProcessDelay {
+		t.Errorf("saved token %q and process delay %d, want the values from the file", saved.Station.Token, saved.Intervals.ProcessDelay)
+	}
+	if saved.Paths.Watch != loaded.Paths.Watch {
+		t.Errorf("saved watch path = %q, want %q", saved.Paths.Watch, loaded.Paths.Watch)
+	}
+}
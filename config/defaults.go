@@ -1,5 +1,7 @@
 package config
 
+import "path/filepath"
+
 const (
 	// DefaultAPIURL is the default SatHub API endpoint
 	DefaultAPIURL = "https://api.sathub.de"
@@ -10,6 +12,82 @@ const (
 	// DefaultProcessDelay is the default delay before processing new directories in seconds
 	DefaultProcessDelay = 60
 
+	// DefaultRecursiveDepth is the default number of directory levels searched for passes below a watch path
+	DefaultRecursiveDepth = 1
+
+	// MaxRecursiveDepth is the largest allowed recursive_depth
+	MaxRecursiveDepth = 5
+
+	// DefaultPollingInterval is the default interval between scans of the watch directories in seconds when polling
+	DefaultPollingInterval = 5
+
+	// DefaultStatusUpdateInterval is the default interval between status updates sent over the WebSocket in seconds
+	DefaultStatusUpdateInterval = 60
+
+	// DefaultCompletenessTimeout is the default time in minutes an incomplete pass directory may stay unchanged
+	DefaultCompletenessTimeout = 30
+
+	// DefaultShutdownTimeout is the default time to wait for in-flight uploads on shutdown in seconds
+	DefaultShutdownTimeout = 120
+
+	// DefaultLogMaxSizeMB is the default log file size in megabytes above which it is rotated at startup
+	DefaultLogMaxSizeMB = 10
+
+	// DefaultMinFreeDiskMB is the default free space in megabytes on the watch partition below which a warning is logged
+	DefaultMinFreeDiskMB = 500
+
+	// DefaultMaxRetries is the default number of attempts for a failed pass before it is given up
+	DefaultMaxRetries = 5
+
+	// DefaultMetadataMaxValueLen is the default length in bytes above which metadata strings are truncated before upload
+	DefaultMetadataMaxValueLen = 4096
+
+	// DefaultProcessedNaming places processed passes directly in the processed directory under their own name
+	DefaultProcessedNaming = "{{.Name}}"
+)
+
+// Default locations follow the XDG Base Directory Specification, see xdgDataHome and xdgConfigHome
+var (
+	// DefaultDataDir is the default location for local state such as upload checksums
+	DefaultDataDir = filepath.Join(xdgDataHome(), "sathub-client")
+
+	// DefaultDeadLetterDir is the default directory for passes that failed too often
+	DefaultDeadLetterDir = filepath.Join(DefaultDataDir, "dead-letter")
+
+	// DefaultPIDFile is the default location of the PID file written by the running client
+	DefaultPIDFile = filepath.Join(DefaultDataDir, "sathub-client.pid")
+
 	// DefaultConfigPath is the default location for the config file
-	DefaultConfigPath = "~/.config/sathub-client/config.yaml"
+	DefaultConfigPath = filepath.Join(xdgConfigHome(), "sathub-client", "config.yaml")
 )
+
+// DefaultImageIncludePatterns are the image file names uploaded by default
+var DefaultImageIncludePatterns = []string{"*.png", "*.tif", "*.tiff"}
+
+// DefaultIQExtensions are the file extensions of the raw I/Q recordings uploaded when upload_iq is enabled
+var DefaultIQExtensions = []string{".wav", ".cf32", ".cs16"}
+
+// DefaultAliases maps common SatDump satellite name variants to their canonical form
+var DefaultAliases = map[string]string{
+	"noaa 15":     "NOAA-15",
+	"noaa15":      "NOAA-15",
+	"noaa_15":     "NOAA-15",
+	"noaa 18":     "NOAA-18",
+	"noaa18":      "NOAA-18",
+	"noaa_18":     "NOAA-18",
+	"noaa 19":     "NOAA-19",
+	"noaa19":      "NOAA-19",
+	"noaa_19":     "NOAA-19",
+	"meteor m2":   "METEOR-M2",
+	"meteor-m 2":  "METEOR-M2",
+	"meteor_m2":   "METEOR-M2",
+	"meteorm2":    "METEOR-M2",
+	"meteor m2-3": "METEOR-M2-3",
+	"meteor-m2 3": "METEOR-M2-3",
+	"meteor_m2-3": "METEOR-M2-3",
+	"meteor_m2_3": "METEOR-M2-3",
+	"meteor m2-4": "METEOR-M2-4",
+	"meteor-m2 4": "METEOR-M2-4",
+	"meteor_m2-4": "METEOR-M2-4",
+	"meteor_m2_4": "METEOR-M2-4",
+}
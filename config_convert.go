@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sathub-client/config"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configConvertTo     string
+	configConvertOutput string
+)
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert --to yaml|toml|json",
+	Short: "Convert the config file to another format",
+	Long:  "Load the config file and write it in another format. The output is written next to the config file with the matching extension unless --output is given. The original file is left in place.",
+	Example: `  # Convert the default config file to ~/.config/sathub-client/config.toml
+  sathub-client config convert --to toml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFromEnv {
+			return fmt.Errorf("--config-env cannot be used with config convert, there is no config file to convert")
+		}
+
+		format := strings.ToLower(configConvertTo)
+		if format == "yml" {
+			format = config.FormatYAML
+		}
+		if format != config.FormatYAML && format != config.FormatTOML && format != config.FormatJSON {
+			return fmt.Errorf("unsupported format %q (supported: %s, %s, %s)", configConvertTo, config.FormatYAML, config.FormatTOML, config.FormatJSON)
+		}
+
+		inputPath := config.GetConfigPath(configPath)
+		// Values from the SATHUB_* environment variables are not part of the file
+		convertCfg, err := config.Read(inputPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		outputPath := configConvertOutput
+		if outputPath == "" {
+			outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "." + format
+		}
+		outputPath = config.GetConfigPath(outputPath)
+		if outputPath == inputPath {
+			return fmt.Errorf("config file is already in %s format", format)
+		}
+
+		convertCfg.SetFormat(format)
+		if err := convertCfg.Save(outputPath); err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(map[string]string{"input": inputPath, "output": outputPath, "format": format})
+			return nil
+		}
+		fmt.Printf("✓ Converted %s to %s\n", inputPath, outputPath)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configConvertCmd)
+	configConvertCmd.Flags().StringVar(&configConvertTo, "to", "", "Format to convert to (yaml, toml or json)")
+	configConvertCmd.Flags().StringVarP(&configConvertOutput, "output", "o", "", "Path to write the converted config file to")
+	configConvertCmd.MarkFlagRequired("to")
+}
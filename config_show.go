@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sathub-client/config"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configRevealToken bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the client configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long:  "Print the configuration as the client uses it, with defaults filled in and paths expanded. The station token is masked unless --reveal-token is given in an interactive terminal.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showCfg, err := readConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if configRevealToken && !isInteractive() {
+			return fmt.Errorf("--reveal-token is only allowed in an interactive terminal")
+		}
+
+		values, err := effectiveConfig(showCfg, configRevealToken)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(values)
+			return nil
+		}
+
+		if configFromEnv {
+			fmt.Print("Config source: environment\n\n")
+		} else {
+			fmt.Printf("Config file: %s\n\n", config.GetConfigPath(configPath))
+		}
+		rows := flattenConfig("", values)
+		keys := make([]string, 0, len(rows))
+		width := 0
+		for key := range rows {
+			keys = append(keys, key)
+			if len(key) > width {
+				width = len(key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%-*s  %s\n", width, key, rows[key])
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	configShowCmd.Flags().BoolVar(&configRevealToken, "reveal-token", false, "Show the full station token (interactive terminals only)")
+}
+
+// effectiveConfig returns the config as a map keyed by YAML names, with paths expanded
+// and the token masked unless revealToken is set
+func effectiveConfig(c *config.Config, revealToken bool) (map[string]interface{}, error) {
+	shown := *c
+	shown.FillDefaults()
+	shown.Paths.Watch = config.ExpandPath(shown.Paths.Watch)
+	shown.Paths.Processed = config.ExpandPath(shown.Paths.Processed)
+	shown.Options.LogFile = config.ExpandPath(shown.Options.LogFile)
+	shown.Options.TLSCACert = config.ExpandPath(shown.Options.TLSCACert)
+	shown.Options.TLSClientCert = config.ExpandPath(shown.Options.TLSClientCert)
+	shown.Options.TLSClientKey = config.ExpandPath(shown.Options.TLSClientKey)
+	shown.Stations = append([]config.StationConfig(nil), c.Stations...)
+	for i := range shown.Stations {
+		shown.Stations[i].Watch = config.ExpandPath(shown.Stations[i].Watch)
+		shown.Stations[i].Processed = config.ExpandPath(shown.Stations[i].Processed)
+	}
+	if !revealToken {
+		shown.Station.Token = maskToken(shown.Station.Token)
+		for i := range shown.Stations {
+			shown.Stations[i].Token = maskToken(shown.Stations[i].Token)
+		}
+	}
+
+	// Round-trip through YAML so keys match the config file
+	data, err := yaml.Marshal(&shown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return values, nil
+}
+
+// flattenConfig turns nested config values into dotted keys such as station.api_url
+func flattenConfig(prefix string, values map[string]interface{}) map[string]string {
+	rows := make(map[string]string)
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for nestedKey, nestedValue := range flattenConfig(key, v) {
+				rows[nestedKey] = nestedValue
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			rows[key] = "[" + strings.Join(items, ", ") + "]"
+		default:
+			rows[key] = fmt.Sprint(v)
+		}
+	}
+	return rows
+}
+
+// isInteractive reports whether stdin and stdout are attached to a terminal
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"sync"
+	"time"
+)
+
+// Commands accepted on the control socket
+const (
+	controlStatus = "status"
+	controlScan   = "scan"
+	controlPause  = "pause"
+	controlResume = "resume"
+	controlReload = "reload"
+)
+
+// controlTimeout limits how long a control connection may take, the commands only trigger work
+const controlTimeout = 10 * time.Second
+
+// errDaemonNotRunning is returned by sendControlCommand when no client is running
+var errDaemonNotRunning = errors.New("sathub-client is not running")
+
+// controlRequest is one newline-delimited command sent to the control socket
+type controlRequest struct {
+	Cmd string `json:"cmd"`
+}
+
+// controlResponse is the reply to a controlRequest, in the same envelope as --json output
+type controlResponse struct {
+	OK    bool            `json:"ok"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// DaemonStatus is the reply to the status command
+type DaemonStatus struct {
+	Version       string          `json:"version"`
+	PID           int             `json:"pid"`
+	StartedAt     time.Time       `json:"started_at"`
+	UptimeSeconds int64           `json:"uptime_seconds"`
+	Stations      []StationStatus `json:"stations"`
+}
+
+// StationStatus describes one station of the running client
+type StationStatus struct {
+	StationID string `json:"station_id"`
+	WatchPath string `json:"watch_path"`
+	Connected bool   `json:"connected"` // WebSocket connection to the server
+	Paused    bool   `json:"paused"`
+
+	Stats WatcherStats `json:"stats"`
+}
+
+// controlSocketPath returns the location of the control socket of the running client
+func controlSocketPath() string {
+	return filepath.Join(config.ExpandPath(config.DefaultDataDir), "control.sock")
+}
+
+// ControlServer accepts commands from the CLI on a Unix socket
+type ControlServer struct {
+	listener net.Listener
+	handler  func(cmd string) (interface{}, error)
+	wg       sync.WaitGroup
+}
+
+// NewControlServer listens on the Unix socket at path and answers commands with handler
+func NewControlServer(path string, handler func(cmd string) (interface{}, error)) (*ControlServer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	// A socket left behind by a client that was killed, the PID file guarantees it is not in use
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove old control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	// Only the user running the client may control it
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket permissions: %w", err)
+	}
+
+	server := &ControlServer{listener: listener, handler: handler}
+	server.wg.Add(1)
+	go server.serve()
+	return server, nil
+}
+
+// serve accepts connections until the listener is closed
+func (s *ControlServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Warn().Err(err).Msg("Control socket stopped accepting connections")
+			}
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn answers the commands on conn, one JSON object per line
+func (s *ControlServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req controlRequest
+		var resp controlResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid command: %v", err)
+		} else if data, err := s.handler(req.Cmd); err != nil {
+			resp.Error = err.Error()
+		} else if resp.Data, err = json.Marshal(data); err != nil {
+			resp.Error = fmt.Sprintf("failed to encode response: %v", err)
+		} else {
+			resp.OK = true
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops accepting commands and removes the socket
+func (s *ControlServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// daemonRunning reports whether the PID file names a running client
+func daemonRunning() bool {
+	pid, err := readPIDFile(pidFilePath())
+	return err == nil && pid != os.Getpid() && processAlive(pid)
+}
+
+// sendControlCommand sends cmd to the running client and decodes the reply data into result,
+// returning errDaemonNotRunning when no client is running
+func sendControlCommand(cmd string, result interface{}) error {
+	if !daemonRunning() {
+		return errDaemonNotRunning
+	}
+
+	conn, err := net.DialTimeout("unix", controlSocketPath(), controlTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the running client: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	if err := json.NewEncoder(conn).Encode(controlRequest{Cmd: cmd}); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	var resp controlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("running client: %s", resp.Error)
+	}
+	if result != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleControlCommand runs a control socket command against the stations of the running client.
+// Reloads are handed to the main loop through reload so they don't race with it.
+func handleControlCommand(cmd string, stations []*StationContext, startedAt time.Time, reload chan<- struct{}) (interface{}, error) {
+	switch cmd {
+	case controlStatus:
+		status := DaemonStatus{
+			Version:       VERSION,
+			PID:           os.Getpid(),
+			StartedAt:     startedAt,
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		}
+		for _, sc := range stations {
+			status.Stations = append(status.Stations, StationStatus{
+				StationID: sc.Station.ID,
+				WatchPath: sc.Station.Watch,
+				Connected: sc.WSClient.IsConnected(),
+				Paused:    sc.Watcher.IsPaused(),
+				Stats:     sc.Watcher.Stats(),
+			})
+		}
+		return status, nil
+
+	case controlScan:
+		for _, sc := range stations {
+			go sc.Watcher.processExistingDirectories()
+		}
+		return map[string]bool{"started": true}, nil
+
+	case controlPause:
+		for _, sc := range stations {
+			sc.Watcher.Pause()
+		}
+		return map[string]bool{"paused": true}, nil
+
+	case controlResume:
+		for _, sc := range stations {
+			sc.Watcher.Resume()
+		}
+		return map[string]bool{"paused": false}, nil
+
+	case controlReload:
+		select {
+		case reload <- struct{}{}:
+		default:
+			// A reload is already pending and will pick up the current file
+		}
+		return map[string]bool{"reloading": true}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown command %q", cmd)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// deadLetterFailureFile is written into each dead-letter pass with its retry history
+const deadLetterFailureFile = "failure.json"
+
+var deadLetterCmd = &cobra.Command{
+	Use:   "dead-letter",
+	Short: "Inspect and retry passes that failed too often",
+	Long:  "Passes that fail options.max_retries times, or never become complete, are moved to paths.dead_letter together with a failure.json holding their error history.",
+}
+
+var deadLetterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List passes in the dead-letter directory",
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := listDeadLetters(newWatcherConfig().DeadLetterDir)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if entries == nil {
+				entries = []RetryEntry{}
+			}
+			PrintJSON(entries)
+			return nil
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No passes in the dead-letter directory.")
+			return nil
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s\n", filepath.Base(entry.Path))
+			fmt.Printf("  Attempts:     %d (last %s)\n", entry.AttemptCount, entry.LastAttempt.Local().Format(time.RFC3339))
+			if entry.FailedStep != "" {
+				fmt.Printf("  Failed step:  %s\n", entry.FailedStep)
+			}
+			if entry.PostID != "" {
+				fmt.Printf("  Post:         %s\n", entry.PostID)
+			}
+			fmt.Printf("  Last error:   %s\n", entry.LastError)
+		}
+		return nil
+	},
+}
+
+var deadLetterRetryCmd = &cobra.Command{
+	Use:   "retry <directory>",
+	Short: "Move a dead-letter pass back to the watch directory",
+	Long:  "Move a pass from the dead-letter directory back to the watch directory, where it is processed again with a fresh retry count. The directory can be given by name or path.",
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		watcherConfig := newWatcherConfig()
+
+		dirPath := filepath.Clean(args[0])
+		if _, err := os.Stat(dirPath); os.IsNotExist(err) && !filepath.IsAbs(dirPath) {
+			dirPath = filepath.Join(watcherConfig.DeadLetterDir, dirPath)
+		}
+		info, err := os.Stat(dirPath)
+		if err != nil {
+			return fmt.Errorf("failed to access directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dirPath)
+		}
+
+		dest := filepath.Join(watcherConfig.WatchPaths[0], filepath.Base(dirPath))
+		if _, err := os.Stat(dest); err == nil {
+			return fmt.Errorf("%s already exists", dest)
+		}
+
+		if dryRun {
+			fmt.Printf("[dry-run] Would move %s to %s\n", dirPath, dest)
+			return nil
+		}
+
+		if err := os.Remove(filepath.Join(dirPath, deadLetterFailureFile)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", deadLetterFailureFile, err)
+		}
+		if err := moveDirectory(dirPath, dest); err != nil {
+			return fmt.Errorf("failed to move directory: %w", err)
+		}
+
+		// Start over with a fresh retry count
+		if retries, err := NewRetryQueue(filepath.Join(watcherConfig.DataDir, "retry-queue.json")); err != nil {
+			logger.Warn().Err(err).Msg("Failed to open retry queue")
+		} else if err := retries.Remove(dest); err != nil {
+			logger.Warn().Err(err).Msg("Failed to reset retry count")
+		}
+
+		if jsonOutput {
+			PrintJSON(map[string]string{"from": dirPath, "to": dest})
+			return nil
+		}
+		fmt.Printf("✓ Moved %s to %s\n", dirPath, dest)
+		return nil
+	},
+}
+
+func init() {
+	deadLetterCmd.AddCommand(deadLetterListCmd)
+	deadLetterCmd.AddCommand(deadLetterRetryCmd)
+}
+
+// listDeadLetters returns the failure records of the passes in deadLetterDir, sorted by directory name
+func listDeadLetters(deadLetterDir string) ([]RetryEntry, error) {
+	dirEntries, err := os.ReadDir(deadLetterDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read dead-letter directory: %w", err)
+	}
+
+	var entries []RetryEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		dirPath := filepath.Join(deadLetterDir, dirEntry.Name())
+
+		entry := RetryEntry{LastError: "no " + deadLetterFailureFile + " found"}
+		if content, err := os.ReadFile(filepath.Join(dirPath, deadLetterFailureFile)); err == nil {
+			if err := json.Unmarshal(content, &entry); err != nil {
+				entry.LastError = fmt.Sprintf("invalid %s: %v", deadLetterFailureFile, err)
+			}
+		}
+		// The recorded path is where the pass failed, report where it is now
+		entry.Path = dirPath
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// errAborted is returned when the user declines a confirmation prompt
+var errAborted = errors.New("aborted")
+
+var deletePostYes bool
+
+var deletePostCmd = &cobra.Command{
+	Use:   "delete-post <post-id>",
+	Short: "Delete an accidentally created post",
+	Long:  "Delete a post from SatHub after confirmation and forget its checksum so the pass can be uploaded again. Exits with 1 on API errors and 2 when the prompt is declined.",
+	Args:  cobra.ExactArgs(1),
+	// Complete with the recent posts of the station
+	ValidArgsFunction: completePostID,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return deletePost(args[0])
+	},
+}
+
+func init() {
+	deletePostCmd.Flags().BoolVarP(&deletePostYes, "yes", "y", false, "Delete without asking for confirmation")
+}
+
+// deletePost deletes a post after confirmation and removes it from the checksum store
+func deletePost(postID string) error {
+	apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	post, err := apiClient.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	if !deletePostYes {
+		fmt.Printf("Delete post %s for satellite %s? [y/N]: ", post.ID, post.SatelliteName)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			return errAborted
+		}
+	}
+
+	if err := apiClient.DeletePost(ctx, postID); err != nil {
+		return err
+	}
+
+	// Forget the checksum so the pass is not matched to the deleted post
+	if !dryRun {
+		dataDir := newWatcherConfig().DataDir
+		checksums, err := NewChecksumStore(filepath.Join(dataDir, "checksums.json"))
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to open checksum store")
+		} else if err := checksums.DeletePost(postID); err != nil {
+			logger.Warn().Err(err).Msg("Failed to remove post from checksum store")
+		}
+	}
+
+	if jsonOutput {
+		PrintJSON(map[string]interface{}{"deleted": postID, "dry_run": dryRun})
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("[dry-run] Would delete post %s\n", postID)
+	} else {
+		fmt.Printf("✓ Deleted post %s\n", postID)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Diagnostic check results
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// diskSpaceWarnBytes is the free space below which the diagnose disk check warns
+const diskSpaceWarnBytes = 1024 * 1024 * 1024
+
+// DiagnosticCheck is the result of a single diagnose check
+type DiagnosticCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+var diagnoseCmd = &cobra.Command{
+	Use:   "diagnose",
+	Short: "Print a system health report",
+	Long:  "Run a series of checks on the configuration, directories, disk space and API connection and print a report with remediation hints for anything that fails.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDiagnose()
+	},
+}
+
+// runDiagnose runs all diagnostic checks and prints the report
+func runDiagnose() error {
+	var checks []DiagnosticCheck
+	add := func(name, status, detail, hint string) {
+		checks = append(checks, DiagnosticCheck{Name: name, Status: status, Detail: detail, Hint: hint})
+	}
+
+	// Config file
+	configFilePath := config.GetConfigPath(configPath)
+	if _, err := os.Stat(configFilePath); err == nil {
+		add("Config file", checkPass, configFilePath, "")
+	} else {
+		add("Config file", checkWarn, fmt.Sprintf("%s does not exist", configFilePath), "A default config will be created, edit it and set your station token")
+	}
+
+	diagCfg, err := readConfig()
+	if err != nil {
+		add("Config parse", checkFail, err.Error(), "Fix the reported error in your config file")
+		return printDiagnostics(checks)
+	}
+	add("Config parse", checkPass, "Configuration is valid", "")
+
+	// Token
+	station := diagCfg.PrimaryStation()
+	token := station.Token
+	switch {
+	case token == "":
+		add("Station token", checkFail, "Token is not set", "Copy the Station API Token from your station page on sathub.de into station.token")
+	case token != strings.TrimSpace(token):
+		add("Station token", checkFail, "Token has leading or trailing whitespace", "Remove the surrounding whitespace from station.token")
+	case strings.ContainsAny(token, " \t\n"):
+		add("Station token", checkFail, "Token contains whitespace", "Make sure the complete token was copied")
+	case len(token) < 16:
+		add("Station token", checkWarn, fmt.Sprintf("Token is only %d characters long", len(token)), "Make sure the complete token was copied")
+	default:
+		add("Station token", checkPass, fmt.Sprintf("%s (%d characters)", maskToken(token), len(token)), "")
+	}
+
+	// Directories
+	for _, dir := range []struct{ name, path string }{
+		{"Watch directory", station.Watch},
+		{"Processed directory", station.Processed},
+	} {
+		status, detail, hint := checkDirectoryWritable(dir.path)
+		add(dir.name, status, detail, hint)
+	}
+
+	// Disk space
+	if free, err := diskFreeBytes(nearestExistingDir(station.Watch)); err != nil {
+		add("Disk space", checkWarn, fmt.Sprintf("Could not determine free space: %v", err), "Make sure the watch directory exists")
+	} else if free < diskSpaceWarnBytes {
+		add("Disk space", checkWarn, fmt.Sprintf("%d MB free on watch partition", free/1024/1024), "Free up disk space or point paths.watch to a larger partition")
+	} else {
+		add("Disk space", checkPass, fmt.Sprintf("%d MB free on watch partition", free/1024/1024), "")
+	}
+
+	// Pending passes
+	if pending, err := countPendingPasses(station.Watch); err != nil {
+		add("Pending passes", checkWarn, fmt.Sprintf("Could not read watch directory: %v", err), "")
+	} else if pending > 0 {
+		add("Pending passes", checkWarn, fmt.Sprintf("%d directories waiting to be processed", pending), "Make sure the client is running, or run 'sathub-client scan' to process them now")
+	} else {
+		add("Pending passes", checkPass, "No unprocessed directories", "")
+	}
+
+	// API
+	stationID := ""
+	apiClient, err := newAPIClient(diagCfg, station)
+	if err != nil {
+		add("TLS configuration", checkFail, err.Error(), "Check the tls_ca_cert, tls_client_cert and tls_client_key options")
+	} else if token != "" {
+		start := time.Now()
+		healthResp, err := apiClient.StationHealth(context.Background())
+		latency := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			add("API connection", checkFail, err.Error(), fmt.Sprintf("Check that %s is reachable and that your station token is correct", station.APIURL))
+		} else {
+			stationID = healthResp.StationID
+			add("API connection", checkPass, fmt.Sprintf("Station %s reachable in %s", stationID, latency), "")
+		}
+	} else {
+		add("API connection", checkWarn, "Skipped, no station token", "Set station.token first")
+	}
+
+	// WebSocket URL
+	if stationID == "" {
+		stationID = "<station-id>"
+	}
+	wsClient := NewWSClient(diagCfg, configPath, stationID)
+	if wsURL, err := wsClient.buildWebSocketURL(); err != nil {
+		add("WebSocket URL", checkFail, err.Error(), "station.api_url must start with http:// or https://")
+	} else {
+		add("WebSocket URL", checkPass, wsURL, "")
+	}
+
+	return printDiagnostics(checks)
+}
+
+// checkDirectoryWritable checks that a directory exists and is writable
+func checkDirectoryWritable(path string) (status, detail, hint string) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return checkWarn, fmt.Sprintf("%s does not exist", path), fmt.Sprintf("It will be created on start, or run: mkdir -p %q", path)
+	}
+	if err != nil {
+		return checkFail, err.Error(), "Check the directory permissions"
+	}
+	if !info.IsDir() {
+		return checkFail, fmt.Sprintf("%s is not a directory", path), "Point the path to a directory"
+	}
+
+	testFile, err := os.CreateTemp(path, ".sathub-diagnose-*")
+	if err != nil {
+		return checkFail, fmt.Sprintf("%s is not writable", path), "Make sure the directory is owned by the user running sathub-client"
+	}
+	testFile.Close()
+	os.Remove(testFile.Name())
+
+	return checkPass, path, ""
+}
+
+// nearestExistingDir returns path or its closest existing parent directory
+func nearestExistingDir(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// countPendingPasses counts the directories in the watch directory that have not been processed yet
+func countPendingPasses(watchPath string) (int, error) {
+	entries, err := os.ReadDir(watchPath)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if _, err := os.Stat(filepath.Join(watchPath, entry.Name(), "dataset.json")); err == nil {
+				pending++
+			}
+		}
+	}
+	return pending, nil
+}
+
+// printDiagnostics prints the check results and returns an error if any check failed
+func printDiagnostics(checks []DiagnosticCheck) error {
+	failed := 0
+	for _, check := range checks {
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		if failed == 0 {
+			PrintJSON(checks)
+		}
+	} else {
+		fmt.Println("SatHub Data Client diagnostics")
+		fmt.Println()
+		for _, check := range checks {
+			fmt.Printf("[%s] %-20s %s\n", check.Status, check.Name, check.Detail)
+			if check.Hint != "" && check.Status != checkPass {
+				fmt.Printf("       %-20s → %s\n", "", check.Hint)
+			}
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d diagnostic check(s) failed", failed)
+	}
+	if !jsonOutput {
+		fmt.Println("All checks passed.")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLowDiskSpace is returned by CheckDiskSpace when free space is below the minimum
+var ErrLowDiskSpace = errors.New("low disk space")
+
+// CheckDiskSpace returns the free space in megabytes on the filesystem containing path,
+// with an error wrapping ErrLowDiskSpace when it is below minFreeMB
+func CheckDiskSpace(path string, minFreeMB int64) (freeMB int64, err error) {
+	free, err := diskFreeBytes(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check disk space: %w", err)
+	}
+
+	freeMB = int64(free / 1024 / 1024)
+	if freeMB < minFreeMB {
+		return freeMB, fmt.Errorf("%w: %d MB free, minimum is %d MB", ErrLowDiskSpace, freeMB, minFreeMB)
+	}
+	return freeMB, nil
+}
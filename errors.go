@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// UploadError is returned when a file of a pass could not be uploaded
+type UploadError struct {
+	FileType string // image, cbor, cadu or iq
+	FilePath string
+	Cause    error
+}
+
+// Error implements the error interface
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("%s upload of %s failed: %v", e.FileType, e.FilePath, e.Cause)
+}
+
+// Unwrap returns the cause of the failed upload
+func (e *UploadError) Unwrap() error {
+	return e.Cause
+}
+
+// ParseError is returned when a metadata file of a pass can't be decoded
+type ParseError struct {
+	File  string
+	Cause error
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.File, e.Cause)
+}
+
+// Unwrap returns the decoding error
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// ValidationError is returned when the content of a pass is decoded but not usable
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// uploadFailures collects the failed uploads of one pass
+type uploadFailures []error
+
+// Error implements the error interface
+func (f uploadFailures) Error() string {
+	return fmt.Sprintf("%d upload(s) failed", len(f))
+}
+
+// Unwrap returns the individual upload errors so errors.Is and errors.As look at each of them
+func (f uploadFailures) Unwrap() []error {
+	return f
+}
+
+// IsRetryable reports whether retrying the operation that returned err can succeed.
+// Files that can't be parsed and requests the API rejects stay broken, everything else
+// (network errors, server errors, rate limits) is assumed to be temporary.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// A pass is worth retrying as long as one of its uploads is
+	var failures uploadFailures
+	if errors.As(err, &failures) {
+		for _, failure := range failures {
+			if IsRetryable(failure) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var parseErr *ParseError
+	var validationErr *ValidationError
+	if errors.As(err, &parseErr) || errors.As(err, &validationErr) || errors.Is(err, ErrInvalidCBOR) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		case http.StatusUnauthorized, http.StatusForbidden:
+			// A bad or revoked token is fixed in the config, not in the pass
+			return true
+		}
+		return apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// IsAuthError reports whether err, or one of the upload errors it holds, is the API rejecting the station token
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventCounter counts file system events by type per directory, it is safe for concurrent use
+type EventCounter struct {
+	mu   sync.RWMutex
+	dirs map[string]*eventCounts
+}
+
+// eventCounts holds the counters of one directory, updated atomically
+type eventCounts struct {
+	create, write, remove, rename, chmod int64
+}
+
+// DirEventCounts is a snapshot of the event counts of one directory
+type DirEventCounts struct {
+	Dir    string `json:"dir"`
+	Create int64  `json:"create"`
+	Write  int64  `json:"write"`
+	Remove int64  `json:"remove"`
+	Rename int64  `json:"rename"`
+	Chmod  int64  `json:"chmod"`
+	Total  int64  `json:"total"`
+}
+
+// NewEventCounter creates an empty event counter
+func NewEventCounter() *EventCounter {
+	return &EventCounter{dirs: make(map[string]*eventCounts)}
+}
+
+// Record counts event for the directory containing the changed file
+func (c *EventCounter) Record(event fsnotify.Event) {
+	counts := c.countsFor(filepath.Dir(event.Name))
+	if event.Has(fsnotify.Create) {
+		atomic.AddInt64(&counts.create, 1)
+	}
+	if event.Has(fsnotify.Write) {
+		atomic.AddInt64(&counts.write, 1)
+	}
+	if event.Has(fsnotify.Remove) {
+		atomic.AddInt64(&counts.remove, 1)
+	}
+	if event.Has(fsnotify.Rename) {
+		atomic.AddInt64(&counts.rename, 1)
+	}
+	if event.Has(fsnotify.Chmod) {
+		atomic.AddInt64(&counts.chmod, 1)
+	}
+}
+
+// countsFor returns the counters of dir, creating them on first use
+func (c *EventCounter) countsFor(dir string) *eventCounts {
+	c.mu.RLock()
+	counts, ok := c.dirs[dir]
+	c.mu.RUnlock()
+	if ok {
+		return counts
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if counts, ok := c.dirs[dir]; ok {
+		return counts
+	}
+	counts = &eventCounts{}
+	c.dirs[dir] = counts
+	return counts
+}
+
+// Snapshot returns the current counts, busiest directory first
+func (c *EventCounter) Snapshot() []DirEventCounts {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make([]DirEventCounts, 0, len(c.dirs))
+	for dir, counts := range c.dirs {
+		entry := DirEventCounts{
+			Dir:    dir,
+			Create: atomic.LoadInt64(&counts.create),
+			Write:  atomic.LoadInt64(&counts.write),
+			Remove: atomic.LoadInt64(&counts.remove),
+			Rename: atomic.LoadInt64(&counts.rename),
+			Chmod:  atomic.LoadInt64(&counts.chmod),
+		}
+		entry.Total = entry.Create + entry.Write + entry.Remove + entry.Rename + entry.Chmod
+		snapshot = append(snapshot, entry)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Total != snapshot[j].Total {
+			return snapshot[i].Total > snapshot[j].Total
+		}
+		return snapshot[i].Dir < snapshot[j].Dir
+	})
+	return snapshot
+}
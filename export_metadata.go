@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+
+	"github.com/spf13/cobra"
+)
+
+// MetadataExport is the post a pass directory would create and the files that would be attached to it
+type MetadataExport struct {
+	Path        string       `json:"path"`
+	PostRequest PostRequest  `json:"post_request"`
+	Files       []ExportFile `json:"files"`
+}
+
+// ExportFile is one file that would be uploaded for a pass
+type ExportFile struct {
+	Path    string `json:"path"`
+	Type    string `json:"type"` // cadu, iq, cbor or image
+	Size    int64  `json:"size"`
+	Product string `json:"product,omitempty"` // product directory of images
+}
+
+var exportMetadataCmd = &cobra.Command{
+	Use:   "export-metadata <directory>",
+	Short: "Print the metadata a pass would be uploaded with",
+	Long: `Read a pass directory the same way the watcher does and print the post request that
+would be sent to the API, after satellite aliases and metadata sanitization are applied,
+followed by the files that would be uploaded. Nothing is sent to the API.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDirectory,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportMetadata(args[0])
+	},
+}
+
+// exportMetadata prints the post request and files of the pass in dirPath
+func exportMetadata(dirPath string) error {
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to access directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dirPath)
+	}
+
+	// The config file is only needed for aliases, filters and sanitization, don't create one
+	cfg = config.Default()
+	if configFromEnv {
+		config.ApplyEnvironmentOverrides(cfg)
+	} else if _, err := os.Stat(config.GetConfigPath(configPath)); err == nil {
+		if loaded, err := config.Load(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, using default settings\n", err)
+		} else {
+			cfg = loaded
+		}
+	}
+	fw := &FileWatcher{config: newWatcherConfig(), logger: logger}
+
+	plan, err := fw.preparePass(dirPath)
+	if err != nil {
+		return err
+	}
+
+	export := MetadataExport{Path: dirPath, PostRequest: plan.PostRequest}
+	addFiles := func(fileType string, paths []string) {
+		for _, path := range paths {
+			file := ExportFile{Path: path, Type: fileType, Product: plan.ImageProducts[path]}
+			if info, err := os.Stat(path); err == nil {
+				file.Size = info.Size()
+			}
+			export.Files = append(export.Files, file)
+		}
+	}
+	addFiles("cadu", plan.CADUPaths)
+	addFiles("iq", plan.IQPaths)
+	addFiles("cbor", plan.CBORPaths)
+	addFiles("image", plan.ImagePaths)
+
+	if jsonOutput {
+		PrintJSON(export)
+		return nil
+	}
+
+	printMetadataExport(export)
+	return nil
+}
+
+// printMetadataExport prints the post request as indented JSON followed by the file list
+func printMetadataExport(export MetadataExport) {
+	// Show the metadata as a nested object instead of an escaped string
+	request := struct {
+		PostRequest
+		Metadata interface{} `json:"metadata,omitempty"`
+	}{PostRequest: export.PostRequest, Metadata: export.PostRequest.Metadata}
+	var metadata interface{}
+	if err := json.Unmarshal([]byte(export.PostRequest.Metadata), &metadata); err == nil {
+		request.Metadata = metadata
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false) // keep the <truncated: N bytes> placeholders readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(request); err != nil {
+		PrintError(fmt.Errorf("failed to marshal post request: %w", err))
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("Files that would be uploaded (%d):\n", len(export.Files))
+	var total int64
+	for _, file := range export.Files {
+		total += file.Size
+		name, err := filepath.Rel(export.Path, file.Path)
+		if err != nil {
+			name = file.Path
+		}
+		fmt.Printf("  %-6s %10.1f KB  %s\n", file.Type, float64(file.Size)/1024, name)
+	}
+	fmt.Printf("Total: %.1f MB\n", float64(total)/1024/1024)
+}
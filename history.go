@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sathub-client/config"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit      int
+	historySatellite  string
+	historySince      time.Duration
+	historyFailedOnly bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recently processed passes",
+	Long:  "List the passes processed by this client from the local pass history, newest first. This shows what the client did locally, use show-post to inspect a post on the server.",
+	Example: `  # Show failed NOAA-19 passes of the last week
+  sathub-client history --satellite NOAA-19 --since 168h --failed-only`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := NewSQLiteStore(filepath.Join(config.ExpandPath(config.DefaultDataDir), "passes.db"))
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		filter := PassFilter{
+			Satellite:  historySatellite,
+			FailedOnly: historyFailedOnly,
+			Limit:      historyLimit,
+		}
+		if historySince > 0 {
+			filter.Since = time.Now().Add(-historySince)
+		}
+
+		records, err := store.QueryPasses(filter)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if records == nil {
+				records = []PassRecord{}
+			}
+			PrintJSON(records)
+			return nil
+		}
+
+		if len(records) == 0 {
+			fmt.Println("No matching passes recorded.")
+			return nil
+		}
+
+		fmt.Printf("%-20s  %-16s  %-36s  %6s  %9s  %s\n", "PROCESSED", "SATELLITE", "POST", "IMAGES", "DURATION", "RESULT")
+		for _, record := range records {
+			result := "ok"
+			if !record.Success {
+				result = "failed"
+			}
+			postID := record.PostID
+			if postID == "" {
+				postID = "-"
+			}
+			fmt.Printf("%-20s  %-16s  %-36s  %6d  %9s  %s\n",
+				record.ProcessedAt.Local().Format("2006-01-02 15:04:05"),
+				record.Satellite,
+				postID,
+				record.ImageCount,
+				(time.Duration(record.DurationMS) * time.Millisecond).Round(100*time.Millisecond),
+				result,
+			)
+		}
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of passes to list, 0 for all")
+	historyCmd.Flags().StringVar(&historySatellite, "satellite", "", "Only list passes of this satellite")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only list passes processed within this duration (e.g. 24h)")
+	historyCmd.Flags().BoolVar(&historyFailedOnly, "failed-only", false, "Only list passes that failed to process")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hookTimeout is the maximum time a pre- or post-upload hook may run
+const hookTimeout = 30 * time.Second
+
+// runHook runs a hook command through sh with the given SATHUB_* environment variables
+func runHook(hook string, env map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("hook timed out after %s", hookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("hook failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
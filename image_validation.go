@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// validateImageFile decodes the image at path and returns an error if it is truncated or corrupt.
+// Only PNG files are checked, other formats are accepted as they are.
+func validateImageFile(path string) error {
+	if !strings.EqualFold(filepath.Ext(path), ".png") {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := png.Decode(file); err != nil {
+		return &ParseError{File: path, Cause: err}
+	}
+	return nil
+}
+
+// validateImages decodes the images in parallel and returns the valid ones in their original order
+// together with the number of images that were skipped
+func (fw *FileWatcher) validateImages(imagePaths []string) ([]string, int) {
+	errs := make([]error, len(imagePaths))
+	workers := runtime.NumCPU()
+	if workers > len(imagePaths) {
+		workers = len(imagePaths)
+	}
+
+	paths := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range paths {
+				errs[i] = validateImageFile(imagePaths[i])
+			}
+		}()
+	}
+	for i := range imagePaths {
+		paths <- i
+	}
+	close(paths)
+	wg.Wait()
+
+	valid := make([]string, 0, len(imagePaths))
+	for i, imagePath := range imagePaths {
+		if errs[i] != nil {
+			fw.logger.Warn().Err(errs[i]).Str("image", filepath.Base(imagePath)).Msg("Skipping corrupt image")
+			continue
+		}
+		valid = append(valid, imagePath)
+	}
+	return valid, len(imagePaths) - len(valid)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// encodePNG returns a small valid PNG image
+func encodePNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateImageFile(t *testing.T) {
+	valid := encodePNG(t)
+	// A little-endian TIFF header followed by garbage, as written by a decoder that crashed
+	tiff := append([]byte("II*\x00"), bytes.Repeat([]byte{0xff}, 64)...)
+
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "valid PNG", file: "rgb.png", data: valid},
+		{name: "valid PNG with upper-case extension", file: "RGB.PNG", data: valid},
+		{name: "truncated PNG", file: "rgb.png", data: valid[:len(valid)/2], wantErr: true},
+		{name: "truncated PNG with upper-case extension", file: "RGB.PNG", data: valid[:len(valid)/2], wantErr: true},
+		{name: "empty PNG", file: "rgb.png", data: nil, wantErr: true},
+		{name: "not a PNG", file: "rgb.png", data: []byte("<html>not found</html>"), wantErr: true},
+		{name: "GeoTIFF", file: "avhrr_3a.tif", data: tiff},
+		{name: "JPEG", file: "preview.jpg", data: []byte("not decoded")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := validateImageFile(path)
+			if tt.wantErr {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("validateImageFile() = %v, want a ParseError", err)
+				}
+				if parseErr.File != path {
+					t.Errorf("ParseError.File = %q, want %q", parseErr.File, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("validateImageFile() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestValidateImageFileMissing(t *testing.T) {
+	if err := validateImageFile(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("validateImageFile succeeded for a missing file")
+	}
+}
+
+func TestValidateImagesKeepsOrder(t *testing.T) {
+	dir := t.TempDir()
+	valid := encodePNG(t)
+
+	var paths, want []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, string(rune('a'+i))+".png")
+		data := valid
+		if i%3 == 0 {
+			data = valid[:10]
+		} else {
+			want = append(want, path)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	fw := &FileWatcher{logger: zerolog.Nop()}
+	got, skipped := fw.validateImages(paths)
+	if skipped != len(paths)-len(want) {
+		t.Errorf("skipped %d images, want %d", skipped, len(paths)-len(want))
+	}
+	if len(got) != len(want) {
+		t.Fatalf("validateImages() returned %d images, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("image %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
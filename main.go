@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -9,24 +10,43 @@ import (
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"runtime"
 	"sathub-client/config"
+	"sathub-client/metrics"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/coreos/go-systemd/v22/daemon"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPath string
-	cfg        *config.Config
-	logger     zerolog.Logger
+	configPath    string
+	configFromEnv bool // Build cfg from SATHUB_* variables instead of the config file
+	cfg           *config.Config
+	cfgMu         sync.RWMutex // Protects cfg fields that change at runtime
+	logger        zerolog.Logger
+	dryRun        bool // Preview uploads without sending data
+	forcePID      bool // Start even if the PID file names a running process
+
+	// Command line overrides for config values, applied in memory only
+	flagInsecure bool
+	flagAPIURL   string
+	flagToken    string
+	fileCfg      config.Config // cfg as loaded, before the environment and command line overrides
 )
 
+// logBase is logger without its component field, components such as the watcher add their own
+var logBase zerolog.Logger
+
 var rootCmd = &cobra.Command{
 	Use:   "sathub-client",
 	Short: "SatHub Data Client for uploading satellite captures",
@@ -37,66 +57,221 @@ and uploads them to your SatHub station. Configuration is loaded from a YAML fil
 
   # Run with custom config file
   sathub-client --config /path/to/config.yaml`,
-	PreRun: func(cmd *cobra.Command, args []string) {
-		// Load configuration
-		var err error
-		cfg, err = config.LoadOrDefault(configPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-			os.Exit(1)
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Arguments were parsed successfully, errors from here on are not usage errors
+		cmd.SilenceUsage = true
+	},
+	// Errors are printed by main, as JSON when --json is set
+	SilenceErrors: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Refuse to start next to another instance, both would upload the same passes
+		pidPath := pidFilePath()
+		if err := acquirePIDFile(pidPath, forcePID); err != nil {
+			return err
 		}
+		defer releasePIDFile(pidPath)
 
-		// Validate that token is set
-		if cfg.Station.Token == "" {
-			fmt.Fprintf(os.Stderr, "Error: station token is not configured\n")
-			fmt.Fprintf(os.Stderr, "Please edit your config file at: %s\n", configPath)
-			os.Exit(1)
+		return runClient()
+	},
+}
+
+// readConfig loads the config file, or builds the config from the environment with --config-env,
+// and applies the environment and command line overrides
+func readConfig() (*config.Config, error) {
+	c := config.Default()
+	validate := true
+	if !configFromEnv {
+		var err error
+		if c, validate, err = config.ReadOrDefault(configPath); err != nil {
+			return nil, err
 		}
+	}
+	fileCfg = *c
+	fileCfg.Stations = append([]config.StationConfig(nil), c.Stations...)
+	config.ApplyEnvironmentOverrides(c)
+	applyFlagOverrides(c)
+	// A newly created default config has no token yet, loadConfig explains where to set it
+	if validate {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// applyFlagOverrides sets the config values given with --insecure, --api-url and --token,
+// the station values apply to the first entry of stations when several are configured
+func applyFlagOverrides(c *config.Config) {
+	if flagInsecure {
+		c.Options.Insecure = true
+	}
+	station := &c.Station
+	if len(c.Stations) > 0 {
+		station = &c.Stations[0]
+	}
+	if flagAPIURL != "" {
+		station.APIURL = flagAPIURL
+	}
+	if flagToken != "" {
+		station.Token = flagToken
+	}
+}
+
+// saveConfig writes cfg to the config file, keeping the file's values for fields overridden
+// in the environment or on the command line
+func saveConfig() error {
+	saved := *cfg
+	if flagInsecure {
+		saved.Options.Insecure = fileCfg.Options.Insecure
+	}
+	if flagAPIURL != "" {
+		saved.Station.APIURL = fileCfg.Station.APIURL
+	}
+	if flagToken != "" {
+		saved.Station.Token = fileCfg.Station.Token
+	}
+	saved.Stations = fileCfg.Stations
+	config.RestoreEnvironmentOverrides(&saved, &fileCfg)
+	return saved.Save(configPath)
+}
+
+// loadConfig loads the configuration file and configures the logger
+func loadConfig() error {
+	// Load configuration
+	var err error
+	cfg, err = readConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Validate that token is set
+	if cfg.PrimaryStation().Token == "" {
+		return fmt.Errorf("station token is not configured, please edit your config file at %s", configPath)
+	}
+
+	// Configure logger
+	applyLogLevel(cfg)
 
-		// Configure logger
-		if cfg.Options.Verbose {
-			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	// Configure console output, logs go to stderr when stdout is reserved for JSON
+	consoleOut := os.Stdout
+	if jsonOutput {
+		consoleOut = os.Stderr
+	}
+	var output io.Writer = zerolog.ConsoleWriter{
+		Out:        consoleOut,
+		TimeFormat: time.RFC3339,
+	}
+	if cfg.Options.JSONLogs() {
+		// One JSON object per line for Loki, Elasticsearch or the journal
+		output = consoleOut
+	}
+
+	// Also write JSON logs to file if configured
+	if cfg.Options.LogFile != "" {
+		logFile, err := openLogFile(config.ExpandPath(cfg.Options.LogFile), cfg.Options.LogMaxSizeMB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not open log file: %v\n", err)
 		} else {
-			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+			output = zerolog.MultiLevelWriter(output, logFile)
 		}
+	}
 
-		// Configure console output
-		logger = log.Output(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: time.RFC3339,
-		}).With().
-			Str("component", "client").
-			Logger()
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		return runClient()
-	},
+	logBase = log.Output(output)
+	if cfg.Options.JSONLogs() {
+		// Log aggregation needs to tell versions apart, and the WebSocket client logs through the global logger
+		logBase = logBase.With().Str("version", VERSION).Logger()
+		log.Logger = logBase.With().Str("component", "websocket").Logger()
+	}
+	logger = logBase.With().
+		Str("component", "client").
+		Logger()
+	return nil
+}
+
+// openLogFile opens the log file in append mode, rotating it to <path>.1 first if it exceeds maxSizeMB
+func openLogFile(path string, maxSizeMB int) (*os.File, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = config.DefaultLogMaxSizeMB
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > int64(maxSizeMB)*1024*1024 {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// applyLogLevel sets the global log level from the verbose option
+func applyLogLevel(c *config.Config) {
+	if c.Options.Verbose {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
 }
 
+var versionCheck bool
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
-	Run: func(cmd *cobra.Command, args []string) {
+	Example: `  # Update only when a newer release exists
+  sathub-client version --check || sathub-client update`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if versionCheck {
+			return runVersionCheck()
+		}
+		if jsonOutput {
+			PrintJSON(map[string]string{"version": VERSION})
+			return nil
+		}
 		fmt.Println(VERSION)
 		logger.Info().Str("version", VERSION).Msg("SatHub Data Client")
+		return nil
 	},
 }
 
+var installUser bool
+
 var installCmd = &cobra.Command{
 	Use:   "install",
-	Short: "Install sathub-client to ~/.local/bin",
-	Long:  "Install sathub-client to ~/.local/bin. Checks if current version is newer than installed version.",
+	Short: "Install sathub-client to /usr/bin or ~/.local/bin",
+	Long:  "Install sathub-client to /usr/bin when running as root, or to ~/.local/bin otherwise. Use --user to always install to ~/.local/bin without root. Checks if current version is newer than installed version.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return installBinary()
+		return installBinary(installUser)
 	},
 }
 
+var installInitSystem string
+
 var installServiceCmd = &cobra.Command{
 	Use:   "install-service",
-	Short: "Install and configure systemd user service",
-	Long:  "Install systemd user service for sathub-client and configure station token. Runs as the current user without requiring root privileges.",
+	Short: "Install and configure the system service (systemd user service, OpenRC or launchd)",
+	Long: `Install systemd user service for sathub-client and configure station token. Runs as the current user without requiring root privileges.
+
+Most configuration changes can be applied to the running service without a restart by sending SIGHUP
+(systemctl --user reload sathub-client): intervals, the verbose option and a new watch directory are
+picked up immediately. Changing the station token, API URL or processed directory requires a restart,
+and a previous watch directory stays watched until the service is restarted.
+
+To feed the logs into Loki or Elasticsearch, set options.log_format to json-lines. The installed systemd
+service sends stdout to the journal with StandardOutput=journal, add that line to hand-written unit files.
+"journalctl --user -u sathub-client -o cat" then prints one JSON object per line.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := installService(); err != nil {
+		if err := installService(installInitSystem); err != nil {
 			logger.Fatal().Err(err).Msg("Failed to install service")
 		}
 	},
@@ -116,29 +291,164 @@ var uninstallServiceCmd = &cobra.Command{
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update sathub-client to the latest version",
-	Long:  "Download and install the latest version of sathub-client from the official source.",
+	Long:  "Download the latest release of sathub-client, verify its minisign signature and replace the running binary. Exits with code 2 if the signature does not verify.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return updateClient()
 	},
 }
 
+var uploadMove bool
+
+var uploadCmd = &cobra.Command{
+	Use:   "upload <directory>",
+	Short: "Upload a single satellite pass directory",
+	Long:  "Upload a specific satellite pass directory to SatHub and exit. The directory is left in place unless --move is given.",
+	Example: `  # Re-submit a pass that was already processed
+  sathub-client upload ~/sathub/processed/2025-09-26_13-01_meteor_m2-x_lrpt_137.9\ MHz
+
+  # Upload a pass and move it to the processed directory afterwards
+  sathub-client upload --move /path/to/pass`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDirectory,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return uploadDirectory(args[0], uploadMove)
+	},
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Process all pending satellite passes and exit",
+	Long:  "Process every complete satellite pass in the watch directory once and exit. Exits with a non-zero status if any pass failed to upload, making it suitable for cron jobs.",
+	Example: `  # Process pending passes every 15 minutes from cron
+  */15 * * * * sathub-client scan --config ~/.config/sathub-client/config.yaml`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Let the running client scan so passes are not processed twice
+		if !dryRun {
+			err := sendControlCommand(controlScan, nil)
+			if err == nil {
+				if jsonOutput {
+					PrintJSON(map[string]interface{}{"delegated": true})
+				} else {
+					fmt.Println("Scan started by the running client, see its logs for the results.")
+				}
+				return nil
+			}
+			if !errors.Is(err, errDaemonNotRunning) {
+				logger.Warn().Err(err).Msg("Could not reach the running client, scanning directly")
+			}
+		}
+		return scanDirectories()
+	},
+}
+
+var (
+	reprocessForce bool
+	reprocessSince time.Duration
+)
+
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess [directory]",
+	Short: "Re-upload passes from the processed directory",
+	Long:  "Re-upload a pass directory (typically inside the processed directory) without moving it, or all processed passes newer than --since in chronological order.",
+	Example: `  # Re-upload a single processed pass
+  sathub-client reprocess ~/sathub/processed/2025-09-26_13-01_meteor_m2-x_lrpt_137.9\ MHz
+
+  # Re-upload everything processed in the last 24 hours
+  sathub-client reprocess --since 24h`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && reprocessSince == 0 {
+			return fmt.Errorf("either a directory or --since is required")
+		}
+		if len(args) == 1 && reprocessSince != 0 {
+			return fmt.Errorf("a directory and --since cannot be combined")
+		}
+
+		var dirs []string
+		if len(args) == 1 {
+			dirs = []string{filepath.Clean(args[0])}
+		} else {
+			var err error
+			dirs, err = processedDirectoriesSince(cfg.PrimaryStation().Processed, reprocessSince)
+			if err != nil {
+				return err
+			}
+		}
+		return reprocessDirectories(dirs, reprocessForce)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(installServiceCmd)
 	rootCmd.AddCommand(uninstallServiceCmd)
 	rootCmd.AddCommand(updateCmd)
-
-	// Only flag is --config for specifying config file location
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", config.DefaultConfigPath, "Path to configuration file")
+	rootCmd.AddCommand(updateCheckCmd)
+	rootCmd.AddCommand(validateDirectoryCmd)
+	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(showPostCmd)
+	rootCmd.AddCommand(deletePostCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(deadLetterCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(uploadCmd)
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(diagnoseCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(reprocessCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(watchStatsCmd)
+	rootCmd.AddCommand(exportMetadataCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(benchmarkCmd)
+
+	// --config is shared by the daemon and all commands that talk to the API
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", config.DefaultConfigPath, "Path to configuration file")
+	rootCmd.PersistentFlags().BoolVar(&configFromEnv, "config-env", false, "Build the configuration from SATHUB_* environment variables instead of a config file")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON output")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Log what would be uploaded without sending data or moving directories")
+	rootCmd.Flags().BoolVar(&forcePID, "force-pid", false, "Start even if the PID file belongs to a running process, e.g. a stale PID file")
+	rootCmd.PersistentFlags().BoolVar(&flagInsecure, "insecure", false, "Allow insecure HTTPS connections, overrides options.insecure (not saved)")
+	rootCmd.PersistentFlags().StringVar(&flagAPIURL, "api-url", "", "SatHub API URL, overrides station.api_url (not saved)")
+	rootCmd.PersistentFlags().StringVar(&flagToken, "token", "", "Station token, overrides station.token (not saved)")
+
+	installServiceCmd.Flags().StringVar(&installInitSystem, "init-system", "", "Init system to install for (systemd, openrc or launchd), detected automatically if empty")
+
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Compare with the latest release and exit with 1 when an update is available, the result is cached for 24 hours")
+
+	installCmd.Flags().BoolVar(&installUser, "user", false, "Install to ~/.local/bin for the current user only")
+
+	uploadCmd.Flags().BoolVar(&uploadMove, "move", false, "Move the directory to the processed directory after a successful upload")
+
+	reprocessCmd.Flags().BoolVar(&reprocessForce, "force", false, "Skip the complete satellite pass check")
+	reprocessCmd.Flags().DurationVar(&reprocessSince, "since", 0, "Reprocess all processed passes modified within this duration (e.g. 24h)")
 }
 
+// maxHealthCheckFailures is the number of consecutive failed health checks
+// after which the systemd watchdog is no longer notified
+const maxHealthCheckFailures = 3
+
 func runClient() error {
-	logger.Info().
-		Str("version", VERSION).
-		Str("api_url", cfg.Station.APIURL).
-		Str("watch_path", cfg.Paths.Watch).
-		Str("processed_dir", cfg.Paths.Processed).
+	startEvent := logger.Info()
+	if !cfg.Options.JSONLogs() {
+		// JSON log lines all carry the version
+		startEvent = startEvent.Str("version", VERSION)
+	}
+	startEvent.
+		Int("stations", len(cfg.StationConfigs())).
 		Msg("Starting SatHub Data Client")
 
 	// Log intervals
@@ -147,138 +457,607 @@ func runClient() error {
 		Int("process_delay", cfg.Intervals.ProcessDelay).
 		Msg("Configuration parameters")
 
-	// Create configuration for watcher (uses old Config struct)
-	watcherConfig := NewConfig(
-		cfg.Station.APIURL,
-		cfg.Station.Token,
-		cfg.Paths.Watch,
-		cfg.Paths.Processed,
-		time.Duration(cfg.Intervals.ProcessDelay)*time.Second,
-	)
-
-	// Create API client
-	apiClient := NewAPIClient(cfg.Station.APIURL, cfg.Station.Token, cfg.Options.Insecure)
-
-	// Test API connection with health check
-	logger.Info().Msg("Testing API connection...")
-	healthResp, err := apiClient.StationHealth()
-	if err != nil {
-		return fmt.Errorf("initial health check failed: %w", err)
+	// Missing directories and odd tokens don't stop the client, but are the usual reason nothing gets uploaded
+	for _, warning := range cfg.ValidateRuntime() {
+		logger.Warn().Str("field", warning.Field).Msg(warning.Message)
 	}
 
-	// Update config with server settings
-	watcherConfig.UpdateFromServerSettings(healthResp.Settings)
-	logger.Info().Msg("Applied server settings to configuration")
+	// Check for a newer version without delaying startup
+	if cfg.Options.CheckUpdates {
+		go logUpdateCheck()
+	}
 
-	// Create file watcher
-	watcher, err := NewFileWatcher(watcherConfig, apiClient)
-	if err != nil {
-		return fmt.Errorf("failed to create file watcher: %w", err)
+	// Start metrics server if configured
+	var collector *metrics.Collector
+	if cfg.Options.MetricsAddr != "" {
+		collector = metrics.NewCollector()
+		go func() {
+			logger.Info().Str("addr", cfg.Options.MetricsAddr).Msg("Serving Prometheus metrics")
+			if err := collector.ListenAndServe(cfg.Options.MetricsAddr); err != nil {
+				logger.Error().Err(err).Msg("Metrics server stopped")
+			}
+		}()
 	}
 
-	// Start the watcher
-	if err := watcher.Start(); err != nil {
-		return fmt.Errorf("failed to start file watcher: %w", err)
+	if cfg.Options.ValidateAPIReachability {
+		logger.Info().Msg("Checking that the API hosts are reachable...")
+		if err := cfg.CheckAPIReachability(); err != nil {
+			return err
+		}
 	}
 
-	// Initialize WebSocket client
-	wsClient := NewWSClient(cfg, configPath, healthResp.StationID)
+	// Test the API connection of every station and create their watchers
+	logger.Info().Msg("Testing API connection...")
+	stations, err := newStationContexts(collector)
+	if err != nil {
+		return err
+	}
+	logger.Info().Msg("Applied server settings to configuration")
 
-	// Periodic health check ticker (may be updated by WebSocket settings)
+	// Periodic health check ticker shared by all stations (may be updated by WebSocket settings)
 	ticker := time.NewTicker(time.Duration(cfg.Intervals.HealthCheck) * time.Second)
 	defer ticker.Stop()
 
-	// Set up WebSocket callbacks
-	wsClient.SetOnSettingsUpdate(func(settings *SettingsUpdatePayload) {
-		logger.Info().
-			Int("health_check_interval", settings.HealthCheckInterval).
-			Int("process_delay", settings.ProcessDelay).
-			Msg("Received settings update from server")
+	// Regular status updates give the server a heartbeat with disk space and pass counts
+	statusInterval := cfg.Intervals.StatusUpdate
+	if statusInterval <= 0 {
+		statusInterval = config.DefaultStatusUpdateInterval
+	}
+	statusTicker := time.NewTicker(time.Duration(statusInterval) * time.Second)
+	defer statusTicker.Stop()
 
-		// Update in-memory config
-		cfg.Intervals.HealthCheck = settings.HealthCheckInterval
-		cfg.Intervals.ProcessDelay = settings.ProcessDelay
+	// Restart signal channel
+	restartChan := make(chan struct{})
 
-		// Update watcher config
-		watcherConfig.ProcessDelay = time.Duration(settings.ProcessDelay) * time.Second
+	for _, sc := range stations {
+		// Warn early if the watch partition is already low on space
+		if freeMB, err := CheckDiskSpace(nearestExistingDir(sc.Station.Watch), sc.WatcherConfig.MinFreeDiskMB); err != nil {
+			sc.logger.Warn().Err(err).Int64("free_mb", freeMB).Msg("Disk space check failed")
+		}
 
-		// Save to disk
-		if err := cfg.Save(configPath); err != nil {
-			logger.Error().Err(err).Msg("Failed to save updated configuration")
-		} else {
-			logger.Info().Msg("Configuration updated and saved")
+		// Start the watcher
+		if err := sc.Watcher.Start(); err != nil {
+			stopStations(stations)
+			return fmt.Errorf("failed to start file watcher: %w", err)
 		}
 
-		// Reset health check ticker with new interval
-		ticker.Reset(time.Duration(settings.HealthCheckInterval) * time.Second)
-		logger.Info().Int("interval", settings.HealthCheckInterval).Msg("Health check interval updated")
-	})
+		sc.WSClient.SetOnSettingsUpdate(settingsUpdateHandler(sc, ticker))
+		// Report the current state right away instead of waiting for the server to ask
+		sc.WSClient.SetOnConnect(sc.WSClient.SendStatusUpdate)
+		stationLogger := sc.logger
+		sc.WSClient.SetOnDisconnect(func(reason error) {
+			stationLogger.Info().Err(reason).Msg("WebSocket disconnected, reconnecting")
+		})
+		sc.WSClient.SetOnRestart(func() {
+			logger.Info().Msg("Received restart command from server")
+			// Signal the main loop to restart
+			select {
+			case restartChan <- struct{}{}:
+			default:
+				logger.Warn().Msg("Restart already in progress")
+			}
+		})
+
+		// Start WebSocket connection (runs in background with auto-reconnect)
+		sc.WSClient.Start()
+		defer sc.WSClient.Stop()
+	}
 
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Restart signal channel
-	restartChan := make(chan struct{})
+	// Reload config on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
-	wsClient.SetOnRestart(func() {
-		logger.Info().Msg("Received restart command from server")
-		// Signal the main loop to restart
-		select {
-		case restartChan <- struct{}{}:
-		default:
-			logger.Warn().Msg("Restart already in progress")
-		}
+	// Accept commands from the CLI, the client works without it
+	controlReload := make(chan struct{}, 1)
+	startedAt := time.Now()
+	controlServer, err := NewControlServer(controlSocketPath(), func(cmd string) (interface{}, error) {
+		return handleControlCommand(cmd, stations, startedAt, controlReload)
 	})
-
-	// Start WebSocket connection (runs in background with auto-reconnect)
-	wsClient.Start()
-	defer wsClient.Stop()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Control socket unavailable, CLI commands will not reach this client")
+	} else {
+		defer controlServer.Close()
+	}
 
 	logger.Info().Msg("SatHub Data Client started successfully")
 
+	// Tell systemd the client is ready (no-op when not started by systemd)
+	daemon.SdNotify(false, daemon.SdNotifyReady)
+
+	// Feed the systemd watchdog more often than its timeout, since the
+	// health check interval is usually much longer than WatchdogSec
+	var watchdogC <-chan time.Time
+	if interval, err := daemon.SdWatchdogEnabled(false); err == nil && interval > 0 {
+		watchdogTicker := time.NewTicker(interval / 2)
+		defer watchdogTicker.Stop()
+		watchdogC = watchdogTicker.C
+	}
+	healthChecks := 0
+
 	for {
 		select {
 		case sig := <-sigChan:
 			logger.Info().Str("signal", sig.String()).Msg("Received shutdown signal")
-			watcher.Stop()
+			daemon.SdNotify(false, daemon.SdNotifyStopping)
+			stopStations(stations)
 			return nil
 
+		case <-watchdogC:
+			// Stop feeding the watchdog once health checks keep failing so
+			// systemd restarts the service
+			if !healthCheckStalled(stations) {
+				daemon.SdNotify(false, daemon.SdNotifyWatchdog)
+			}
+
+		case <-reloadChan:
+			logger.Info().Msg("Received SIGHUP, reloading configuration")
+			reloadConfig(stations, ticker)
+
+		case <-controlReload:
+			logger.Info().Msg("Reload requested on control socket, reloading configuration")
+			reloadConfig(stations, ticker)
+
 		case <-restartChan:
 			logger.Info().Msg("Restart requested, shutting down gracefully...")
-			watcher.Stop()
+			stopStations(stations)
 			// Note: When running as systemd service with Restart=always,
 			// the service will automatically restart. When running manually,
 			// you'll need to restart it yourself.
 			return fmt.Errorf("restart requested")
 
+		case <-statusTicker.C:
+			for _, sc := range stations {
+				if sc.WSClient.IsConnected() {
+					sc.WSClient.SendStatusUpdate()
+				}
+			}
+
 		case <-ticker.C:
-			healthResp, err := apiClient.StationHealth()
-			if err != nil {
-				// Retry once after a brief delay
-				time.Sleep(1 * time.Second)
-				healthResp, err = apiClient.StationHealth()
-				if err != nil {
-					logger.Warn().Err(err).Msg("Health check failed after retry")
-					continue
+			if !checkStationsHealth(stations, collector) {
+				continue
+			}
+			daemon.SdNotify(false, daemon.SdNotifyWatchdog)
+
+			// Apply the retention policy to the processed directories every few cycles
+			healthChecks++
+			if healthChecks%cleanupEveryHealthChecks == 0 {
+				cfgMu.RLock()
+				policy := retentionPolicyFromConfig(cfg)
+				cfgMu.RUnlock()
+				if policy.enabled() {
+					cleaned := make(map[string]bool)
+					for _, sc := range stations {
+						if cleaned[sc.Station.Processed] {
+							continue
+						}
+						cleaned[sc.Station.Processed] = true
+						if _, err := runCleanup(sc.Station.Processed, policy, false); err != nil {
+							sc.logger.Warn().Err(err).Msg("Processed directory cleanup failed")
+						}
+					}
 				}
 			}
-			// Update config with server settings
-			watcherConfig.UpdateFromServerSettings(healthResp.Settings)
-			logger.Info().Msg("Health check successful")
 		}
 	}
 }
 
-// installBinary installs the current binary to ~/.local/bin/sathub-client
-func installBinary() error {
-	// Get current user
-	currentUser, err := user.Current()
+// settingsUpdateHandler returns the callback applying settings pushed by the server for sc
+func settingsUpdateHandler(sc *StationContext, ticker *time.Ticker) func(*SettingsUpdatePayload) {
+	return func(settings *SettingsUpdatePayload) {
+		sc.logger.Info().
+			Int("health_check_interval", settings.HealthCheckInterval).
+			Int("process_delay", settings.ProcessDelay).
+			Msg("Received settings update from server")
+
+		cfgMu.Lock()
+		defer cfgMu.Unlock()
+
+		// Update watcher config
+		sc.WatcherConfig.SetProcessDelay(time.Duration(settings.ProcessDelay) * time.Second)
+
+		// The intervals in the config file are shared, a single station can only change its own process delay
+		if multiStation() {
+			sc.logger.Info().Msg("Process delay updated (health check interval is shared by all stations and not changed)")
+			return
+		}
+
+		// Update in-memory config
+		cfg.Intervals.HealthCheck = settings.HealthCheckInterval
+		cfg.Intervals.ProcessDelay = settings.ProcessDelay
+
+		// Restore the configured log level in case it was changed by the server
+		applyLogLevel(cfg)
+
+		// Save to disk, there is no file to update when configured from the environment
+		if configFromEnv {
+			logger.Info().Msg("Configuration updated (not saved, loaded from environment)")
+		} else if err := saveConfig(); err != nil {
+			logger.Error().Err(err).Msg("Failed to save updated configuration")
+		} else {
+			logger.Info().Msg("Configuration updated and saved")
+		}
+
+		// Reset health check ticker with new interval
+		ticker.Reset(time.Duration(settings.HealthCheckInterval) * time.Second)
+		logger.Info().Int("interval", settings.HealthCheckInterval).Msg("Health check interval updated")
+	}
+}
+
+// newAPIClient creates an API client for station using the settings from the config file
+func newAPIClient(c *config.Config, station config.StationConfig) (*APIClient, error) {
+	tlsConfig, err := buildTLSConfig(c.Options)
 	if err != nil {
-		return fmt.Errorf("failed to get current user: %w", err)
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	apiClient := NewAPIClient(station.APIURL, station.Token,
+		WithTLSConfig(tlsConfig),
+		WithRateLimit(float64(c.Options.MaxUploadBytesPerSecond)),
+	)
+	apiClient.SetTimeouts(
+		time.Duration(c.Options.ConnectTimeoutSec)*time.Second,
+		time.Duration(c.Options.HealthCheckTimeoutSec)*time.Second,
+		time.Duration(c.Options.UploadTimeoutPerMBSec*float64(time.Second)),
+	)
+	apiClient.SetValidateCBOR(c.Options.CBORValidationEnabled())
+	apiClient.SetDryRun(dryRun)
+	return apiClient, nil
+}
+
+// newWatcherConfig creates the watcher configuration of the primary station from the loaded config file
+func newWatcherConfig() *Config {
+	return newStationWatcherConfig(cfg.PrimaryStation())
+}
+
+// newStationWatcherConfig creates the watcher configuration of station from the loaded config file
+func newStationWatcherConfig(station config.StationConfig) *Config {
+	watcherConfig := NewConfig(
+		station.APIURL,
+		station.Token,
+		station.Watch,
+		station.Processed,
+		time.Duration(cfg.Intervals.ProcessDelay)*time.Second,
+	)
+	watcherConfig.SatelliteAliases = cfg.SatelliteAliases()
+	watcherConfig.IncludeSatellites = cfg.Filters.Include
+	watcherConfig.ExcludeSatellites = cfg.Filters.Exclude
+	watcherConfig.ImageInclude = cfg.Filters.ImageInclude
+	watcherConfig.ImageExclude = cfg.Filters.ImageExclude
+	watcherConfig.UploadIQ = cfg.Filters.UploadIQ
+	watcherConfig.IQExtensions = cfg.Filters.IQExtensions
+	if len(watcherConfig.IQExtensions) == 0 {
+		watcherConfig.IQExtensions = config.DefaultIQExtensions
+	}
+	watcherConfig.PreUploadHook = cfg.Hooks.PreUpload
+	watcherConfig.PostUploadHook = cfg.Hooks.PostUpload
+	watcherConfig.DryRun = dryRun
+	watcherConfig.DataDir = stationDataDir(station)
+	watcherConfig.HistoryDB = filepath.Join(config.ExpandPath(config.DefaultDataDir), "passes.db")
+	watcherConfig.MinFreeDiskMB = cfg.Options.MinFreeDiskMB
+	if watcherConfig.MinFreeDiskMB <= 0 {
+		watcherConfig.MinFreeDiskMB = config.DefaultMinFreeDiskMB
+	}
+	watcherConfig.CompressProcessed = cfg.Cleanup.Compress
+	watcherConfig.ValidateImages = cfg.Options.ValidateImages
+	watcherConfig.ProcessedNaming = cfg.Paths.ProcessedNaming
+	watcherConfig.MetadataMaxValueLen = cfg.Options.MetadataMaxValueLen
+	if watcherConfig.MetadataMaxValueLen <= 0 {
+		watcherConfig.MetadataMaxValueLen = config.DefaultMetadataMaxValueLen
+	}
+	watcherConfig.RecursiveDepth = cfg.Options.RecursiveDepth
+	if watcherConfig.RecursiveDepth <= 0 {
+		watcherConfig.RecursiveDepth = config.DefaultRecursiveDepth
+	}
+	watcherConfig.CompletionSentinel = cfg.Options.CompletionSentinel
+	watcherConfig.UsePolling = cfg.Options.UsePolling
+	watcherConfig.PollingInterval = time.Duration(cfg.Options.PollingIntervalSec) * time.Second
+	if watcherConfig.PollingInterval <= 0 {
+		watcherConfig.PollingInterval = config.DefaultPollingInterval * time.Second
+	}
+	watcherConfig.MaxRetries = cfg.Options.MaxRetries
+	if watcherConfig.MaxRetries <= 0 {
+		watcherConfig.MaxRetries = config.DefaultMaxRetries
+	}
+	watcherConfig.DeadLetterDir = cfg.Paths.DeadLetter
+	if watcherConfig.DeadLetterDir == "" {
+		watcherConfig.DeadLetterDir = config.DefaultDeadLetterDir
+	}
+	watcherConfig.DeadLetterDir = config.ExpandPath(watcherConfig.DeadLetterDir)
+	watcherConfig.ShutdownTimeout = time.Duration(cfg.Intervals.ShutdownTimeout) * time.Second
+	if watcherConfig.ShutdownTimeout <= 0 {
+		watcherConfig.ShutdownTimeout = config.DefaultShutdownTimeout * time.Second
+	}
+	watcherConfig.CompletenessTimeout = time.Duration(cfg.Intervals.CompletenessTimeout) * time.Minute
+	if watcherConfig.CompletenessTimeout <= 0 {
+		watcherConfig.CompletenessTimeout = config.DefaultCompletenessTimeout * time.Minute
+	}
+	return watcherConfig
+}
+
+// reloadConfig re-reads the config file and applies the settings that can change without a restart
+func reloadConfig(stations []*StationContext, ticker *time.Ticker) {
+	var newCfg *config.Config
+	var err error
+	if configFromEnv {
+		newCfg, err = config.FromEnvironment()
+	} else {
+		newCfg, err = config.Load(configPath)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to reload configuration, keeping current settings")
+		return
+	}
+	applyFlagOverrides(newCfg)
+
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+
+	if newCfg.Station.Token != cfg.Station.Token || newCfg.Station.APIURL != cfg.Station.APIURL {
+		logger.Warn().Msg("Station token or API URL changed, restart required to apply")
+	}
+	if !reflect.DeepEqual(newCfg.Stations, cfg.Stations) {
+		logger.Warn().Msg("Stations changed, restart required to apply")
+	}
+	if newCfg.Paths.Processed != cfg.Paths.Processed {
+		logger.Warn().Str("processed_dir", newCfg.Paths.Processed).Msg("Processed directory changed, restart required to apply")
+	}
+
+	// Intervals
+	cfg.Intervals.HealthCheck = newCfg.Intervals.HealthCheck
+	cfg.Intervals.ProcessDelay = newCfg.Intervals.ProcessDelay
+	for _, sc := range stations {
+		sc.WatcherConfig.SetProcessDelay(time.Duration(newCfg.Intervals.ProcessDelay) * time.Second)
+	}
+	ticker.Reset(time.Duration(newCfg.Intervals.HealthCheck) * time.Second)
+
+	// Logging
+	cfg.Options.Verbose = newCfg.Options.Verbose
+	applyLogLevel(cfg)
+
+	// Satellite aliases
+	cfg.Satellites = newCfg.Satellites
+	for _, sc := range stations {
+		sc.WatcherConfig.SetSatelliteAliases(cfg.SatelliteAliases())
+	}
+
+	// Watch path, the previous path stays active until restart. Stations
+	// without their own watch path need a restart to pick it up.
+	if newCfg.Paths.Watch != cfg.Paths.Watch && !multiStation() {
+		if err := stations[0].Watcher.AddWatchPath(newCfg.Paths.Watch); err != nil {
+			logger.Error().Err(err).Str("path", newCfg.Paths.Watch).Msg("Failed to watch new directory")
+		} else {
+			logger.Warn().Str("path", cfg.Paths.Watch).Msg("Previous watch directory remains active until restart")
+			cfg.Paths.Watch = newCfg.Paths.Watch
+		}
+	} else if newCfg.Paths.Watch != cfg.Paths.Watch {
+		logger.Warn().Str("path", newCfg.Paths.Watch).Msg("Watch directory changed, restart required to apply to stations")
+	}
+
+	logger.Info().
+		Int("health_check_interval", cfg.Intervals.HealthCheck).
+		Int("process_delay", cfg.Intervals.ProcessDelay).
+		Bool("verbose", cfg.Options.Verbose).
+		Msg("Configuration reloaded")
+}
+
+// uploadDirectory runs the full processing pipeline for a single satellite pass directory
+func uploadDirectory(dirPath string, move bool) error {
+	dirPath = filepath.Clean(dirPath)
+
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to access directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dirPath)
+	}
+
+	watcherConfig := newWatcherConfig()
+
+	apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+	if err != nil {
+		return err
+	}
+
+	watcher, err := NewFileWatcher(watcherConfig, apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Stop()
+	watcher.SetForceNewPosts(true)
+
+	if !watcher.isCompleteSatellitePass(dirPath) {
+		return fmt.Errorf("%s is not a complete satellite pass (expected dataset.json and a CADU file or product directory with product.cbor)", dirPath)
+	}
+
+	if !jsonOutput {
+		fmt.Printf("Uploading satellite pass from %s...\n", dirPath)
+	}
+
+	if err := watcher.processPass(dirPath); err != nil {
+		return fmt.Errorf("failed to process satellite pass: %w", err)
+	}
+
+	var movedTo string
+	if move {
+		if movedTo, err = watcher.moveDirectoryToProcessed(dirPath); err != nil {
+			return fmt.Errorf("satellite pass was uploaded, but moving it to processed failed: %w", err)
+		}
+	}
+
+	if jsonOutput {
+		result := map[string]interface{}{
+			"directory": dirPath,
+			"moved":     move && !dryRun,
+			"dry_run":   dryRun,
+		}
+		if move && !dryRun {
+			result["moved_to"] = movedTo
+		}
+		PrintJSON(result)
+		return nil
+	}
+
+	if move && !dryRun {
+		fmt.Printf("Moved directory to %s\n", movedTo)
+	}
+	fmt.Println("Upload completed successfully!")
+	return nil
+}
+
+// scanDirectories processes all pending passes in the watch directory once without
+// starting the fsnotify loop or WebSocket client
+func scanDirectories() error {
+	watcherConfig := newWatcherConfig()
+
+	apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+	if err != nil {
+		return err
+	}
+
+	watcher, err := NewFileWatcher(watcherConfig, apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Stop()
+
+	logger.Info().Str("watch_path", watcherConfig.WatchPaths[0]).Msg("Scanning for pending satellite passes")
+
+	// Passes already in the watch directory are complete, they don't wait for the process delay
+	errs := watcher.scanExistingDirectories(true)
+	if jsonOutput && len(errs) == 0 {
+		PrintJSON(map[string]interface{}{"failed": 0})
+	}
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			logger.Error().Err(err).Msg("Scan failed")
+			msgs[i] = err.Error()
+		}
+		if jsonOutput {
+			// The JSON error on stderr is all a script gets, so it names every error
+			return fmt.Errorf("scan failed with %d error(s): %s", len(errs), strings.Join(msgs, "; "))
+		}
+		return fmt.Errorf("scan failed with %d error(s)", len(errs))
+	}
+
+	logger.Info().Msg("Scan completed successfully")
+	return nil
+}
+
+// processedDirectoriesSince returns the pass directories below processedDir modified within since, oldest first
+func processedDirectoriesSince(processedDir string, since time.Duration) ([]string, error) {
+	passes, err := findProcessedPasses(processedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	type dirInfo struct {
+		path    string
+		modTime time.Time
+	}
+
+	cutoff := time.Now().Add(-since)
+	var found []dirInfo
+	for _, path := range passes {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			found = append(found, dirInfo{path: path, modTime: info.ModTime()})
+		}
 	}
 
-	targetPath := filepath.Join(currentUser.HomeDir, ".local", "bin", "sathub-client")
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].modTime.Before(found[j].modTime)
+	})
+
+	dirs := make([]string, len(found))
+	for i, dir := range found {
+		dirs[i] = dir.path
+	}
+	return dirs, nil
+}
+
+// reprocessDirectories re-uploads the given pass directories without moving them
+func reprocessDirectories(dirs []string, force bool) error {
+	if len(dirs) == 0 {
+		if jsonOutput {
+			PrintJSON(map[string]interface{}{"reprocessed": []string{}, "dry_run": dryRun})
+			return nil
+		}
+		fmt.Println("No passes to reprocess.")
+		return nil
+	}
+
+	apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+	if err != nil {
+		return err
+	}
+
+	watcher, err := NewFileWatcher(newWatcherConfig(), apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Stop()
+	watcher.SetForceNewPosts(true)
+
+	var errs []error
+	reprocessed := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !force && !watcher.isCompleteSatellitePass(dir) {
+			errs = append(errs, fmt.Errorf("%s: not a complete satellite pass (use --force to reprocess anyway)", dir))
+			continue
+		}
+
+		logger.Info().Str("dir", dir).Msg("Reprocessing satellite pass")
+		if err := watcher.processPass(dir); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+			continue
+		}
+		reprocessed = append(reprocessed, dir)
+	}
+
+	if len(errs) > 0 {
+		if jsonOutput {
+			// The JSON error on stderr is all a script gets, so it names every failed pass
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				msgs[i] = err.Error()
+			}
+			return fmt.Errorf("%d of %d pass(es) failed to reprocess: %s", len(errs), len(dirs), strings.Join(msgs, "; "))
+		}
+		fmt.Printf("\n%d of %d pass(es) failed:\n", len(errs), len(dirs))
+		for _, err := range errs {
+			fmt.Printf("  - %v\n", err)
+		}
+		return fmt.Errorf("%d pass(es) failed to reprocess", len(errs))
+	}
+
+	if jsonOutput {
+		PrintJSON(map[string]interface{}{"reprocessed": reprocessed, "dry_run": dryRun})
+		return nil
+	}
+	fmt.Printf("Reprocessed %d pass(es) successfully.\n", len(dirs))
+	return nil
+}
+
+// installBinary installs the current binary to /usr/bin when running as root,
+// or to ~/.local/bin/sathub-client otherwise or when userInstall is set
+func installBinary(userInstall bool) error {
+	targetPath := "/usr/bin/sathub-client"
+	if userInstall || os.Geteuid() != 0 {
+		// Get current user
+		currentUser, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("failed to get current user: %w", err)
+		}
+		targetPath = filepath.Join(currentUser.HomeDir, ".local", "bin", "sathub-client")
+	}
 
 	// Create the install directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
@@ -292,28 +1071,16 @@ func installBinary() error {
 	}
 
 	// Check if target already exists and compare versions
-	if _, err := os.Stat(targetPath); err == nil {
-		// Get installed version
-		cmd := exec.Command(targetPath, "version")
-		output, err := cmd.Output()
-		if err == nil {
-			installedVersionStr := strings.TrimSpace(string(output))
-			// Extract version from "SatHub Data Client vX.Y.Z"
-			re := regexp.MustCompile(`v(\d+\.\d+\.\d+)`)
-			matches := re.FindStringSubmatch(installedVersionStr)
-			if len(matches) > 1 {
-				installedVersion := matches[1]
-				if compareVersions(VERSION, installedVersion) <= 0 {
-					logger.Info().Str("current_version", VERSION).Str("installed_version", installedVersion).Msg("Current version is not newer than installed version")
-					logger.Info().Msg("Installation cancelled.")
-					return nil
-				}
-				logger.Info().Str("from", installedVersion).Str("to", VERSION).Msg("Upgrading")
-			}
-		}
+	shouldInstall, reason, err := compareInstalledVersion(targetPath)
+	if err != nil {
+		return err
 	}
-
-	logger.Info().Str("version", VERSION).Str("path", targetPath).Msg("Installing sathub-client")
+	if !shouldInstall {
+		logger.Info().Str("current_version", VERSION).Msg(reason)
+		logger.Info().Msg("Installation cancelled.")
+		return nil
+	}
+	logger.Info().Str("version", VERSION).Str("path", targetPath).Msg(reason)
 
 	// Copy current executable to target path
 	if err := copyFile(currentExe, targetPath); err != nil {
@@ -330,87 +1097,112 @@ func installBinary() error {
 	return nil
 }
 
-// updateClient downloads and runs the latest installation script
-func updateClient() error {
-	const installURL = "https://api.sathub.de/install"
-
-	fmt.Printf("Downloading latest version from %s...\n", installURL)
-	fmt.Println()
+// compareInstalledVersion checks whether the binary at targetPath should be replaced by the current version
+func compareInstalledVersion(targetPath string) (shouldInstall bool, reason string, err error) {
+	if _, err := os.Stat(targetPath); os.IsNotExist(err) {
+		return true, "Installing sathub-client", nil
+	} else if err != nil {
+		return false, "", fmt.Errorf("failed to check installed binary: %w", err)
+	}
 
-	// Download script to temporary file to preserve stdin for interactive prompts
-	tmpFile, err := os.CreateTemp("", "sathub-install-*.sh")
+	// Get installed version, an unreadable binary is simply replaced
+	output, err := exec.Command(targetPath, "version").Output()
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return true, "Replacing installed binary with unknown version", nil
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
 
-	// Download script
-	curlCmd := exec.Command("curl", "-sSL", "-o", tmpFile.Name(), installURL)
-	curlCmd.Stdout = os.Stdout
-	curlCmd.Stderr = os.Stderr
-	if err := curlCmd.Run(); err != nil {
-		return fmt.Errorf("failed to download install script: %w", err)
+	// Extract version from "1.2.3", "1.2.3-beta.1" or "SatHub Data Client vX.Y.Z"
+	re := regexp.MustCompile(`v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)`)
+	matches := re.FindStringSubmatch(strings.TrimSpace(string(output)))
+	if len(matches) < 2 {
+		return true, "Replacing installed binary with unknown version", nil
 	}
 
-	// Make executable
-	if err := os.Chmod(tmpFile.Name(), 0755); err != nil {
-		return fmt.Errorf("failed to make script executable: %w", err)
+	installedVersion := matches[1]
+	if compareVersions(VERSION, installedVersion) <= 0 {
+		return false, fmt.Sprintf("Current version is not newer than installed version %s", installedVersion), nil
 	}
+	return true, fmt.Sprintf("Upgrading from %s", installedVersion), nil
+}
 
-	// Run script with stdin connected to terminal
-	bashCmd := exec.Command("bash", tmpFile.Name())
-	bashCmd.Stdout = os.Stdout
-	bashCmd.Stderr = os.Stderr
-	bashCmd.Stdin = os.Stdin
-
-	if err := bashCmd.Run(); err != nil {
-		return fmt.Errorf("update failed: %w", err)
+// installService creates and configures the service for the detected (or requested) init system
+func installService(initSystem string) error {
+	// The service would start without the environment the configuration came from
+	if configFromEnv {
+		return fmt.Errorf("--config-env cannot be used with install-service, the service reads its configuration from %s", config.DefaultConfigPath)
 	}
 
-	return nil
-}
-
-// installService creates and configures the systemd user service
-func installService() error {
 	// Get current user's home directory
 	currentUser, err := user.Current()
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
 
-	// Use user systemd directory
-	systemdUserDir := filepath.Join(currentUser.HomeDir, ".config", "systemd", "user")
-	servicePath := filepath.Join(systemdUserDir, "sathub-client.service")
+	if initSystem == "" {
+		initSystem = detectInitSystem()
+	}
+
 	configFilePath := config.GetConfigPath(config.DefaultConfigPath)
 
-	// Create systemd user directory if it doesn't exist
-	if err := os.MkdirAll(systemdUserDir, 0755); err != nil {
-		return fmt.Errorf("failed to create systemd user directory: %w", err)
+	var servicePath string
+	switch initSystem {
+	case initSystemSystemd:
+		// Use user systemd directory
+		systemdUserDir := filepath.Join(currentUser.HomeDir, ".config", "systemd", "user")
+		servicePath = filepath.Join(systemdUserDir, "sathub-client.service")
+
+		// Create systemd user directory if it doesn't exist
+		if err := os.MkdirAll(systemdUserDir, 0755); err != nil {
+			return fmt.Errorf("failed to create systemd user directory: %w", err)
+		}
+	case initSystemOpenRC:
+		// OpenRC services are system-wide
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("installing an OpenRC service requires root, run: sudo sathub-client install-service")
+		}
+		servicePath = openRCServicePath
+	case initSystemLaunchd:
+		servicePath = launchdPlistPath(currentUser.HomeDir)
+
+		// Create LaunchAgents directory if it doesn't exist
+		if err := os.MkdirAll(filepath.Dir(servicePath), 0755); err != nil {
+			return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported init system %q (supported: %s, %s, %s)", initSystem, initSystemSystemd, initSystemOpenRC, initSystemLaunchd)
 	}
 
-	// Check if binary is installed in /usr/bin or ~/.local/bin
-	binaryPath := "/usr/bin/sathub-client"
-	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
-		// Try local binary path
-		binaryPath = filepath.Join(currentUser.HomeDir, ".local", "bin", "sathub-client")
-		if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
-			return fmt.Errorf("sathub-client is not installed in /usr/bin or ~/.local/bin")
+	// Check common install locations for the binary
+	binaryDirs := []string{
+		"/usr/bin",
+		"/usr/local/bin",
+		"/opt/homebrew/bin",
+		filepath.Join(currentUser.HomeDir, ".local", "bin"),
+	}
+	binaryPath := ""
+	for _, dir := range binaryDirs {
+		candidate := filepath.Join(dir, "sathub-client")
+		if _, err := os.Stat(candidate); err == nil {
+			binaryPath = candidate
+			break
 		}
 	}
+	if binaryPath == "" {
+		return fmt.Errorf("sathub-client is not installed in /usr/bin, /usr/local/bin, /opt/homebrew/bin or ~/.local/bin")
+	}
 
 	// Check if service already exists
 	serviceExists := false
 	if _, err := os.Stat(servicePath); err == nil {
 		serviceExists = true
-		fmt.Println("Systemd service already exists.")
+		fmt.Println("Service already exists.")
 	}
 
 	// Load or create config file
 	var clientConfig *config.Config
 	if _, err := os.Stat(configFilePath); err == nil {
-		// Config exists, load it
-		clientConfig, err = config.Load(configFilePath)
+		// Config exists, read it without the environment overrides so they aren't saved to the file
+		clientConfig, err = config.Read(configFilePath)
 		if err != nil {
 			return fmt.Errorf("failed to load existing config: %w", err)
 		}
@@ -433,29 +1225,19 @@ func installService() error {
 			fmt.Println("Keeping existing configuration.")
 
 			if serviceExists {
-				// Reload systemd user daemon (in case binary was updated)
-				if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
-					fmt.Printf("Warning: failed to reload systemd: %v\n", err)
-				}
-
-				// Try to restart the service
-				if err := exec.Command("systemctl", "--user", "restart", "sathub-client").Run(); err != nil {
-					fmt.Printf("Warning: failed to restart service: %v\n", err)
-					fmt.Println("You may need to run: systemctl --user restart sathub-client")
-				} else {
-					fmt.Println("Service restarted successfully with updated binary.")
-				}
+				restartService(initSystem)
 			} else {
 				// Create new service with existing config
-				if err := createSystemdService(servicePath, binaryPath); err != nil {
+				if err := createService(initSystem, servicePath, binaryPath, configFilePath); err != nil {
 					return err
 				}
-				if err := enableAndStartService(serviceExists); err != nil {
+				if err := startService(initSystem, serviceExists); err != nil {
 					return err
 				}
 			}
 
-			fmt.Println("Use 'systemctl --user status sathub-client' to check service status")
+			printServiceStatusHint(initSystem)
+			offerBashCompletion(currentUser.HomeDir)
 			return nil
 		}
 		fmt.Println()
@@ -484,26 +1266,103 @@ func installService() error {
 	}
 
 	// Generate and write service file
-	if err := createSystemdService(servicePath, binaryPath); err != nil {
+	if err := createService(initSystem, servicePath, binaryPath, configFilePath); err != nil {
 		return err
 	}
 
 	// Enable and start service
-	if err := enableAndStartService(serviceExists); err != nil {
+	if err := startService(initSystem, serviceExists); err != nil {
 		return err
 	}
 
 	fmt.Println()
 	fmt.Println("Service installed and running!")
-	fmt.Println("Use 'systemctl --user status sathub-client' to check service status")
-	fmt.Println("Use 'journalctl --user -u sathub-client -f' to view logs")
-	fmt.Println()
-	fmt.Println("To enable the service to start automatically after reboot (even when not logged in):")
-	fmt.Println("  loginctl enable-linger $USER")
+	printServiceStatusHint(initSystem)
+	switch initSystem {
+	case initSystemOpenRC:
+		fmt.Printf("Logs are written to %s\n", openRCLogPath)
+	case initSystemLaunchd:
+		fmt.Printf("Logs are written to %s\n", launchdLogPath(currentUser.HomeDir))
+	default:
+		fmt.Println("Use 'journalctl --user -u sathub-client -f' to view logs")
+		fmt.Println()
+		fmt.Println("To enable the service to start automatically after reboot (even when not logged in):")
+		fmt.Println("  loginctl enable-linger $USER")
+	}
 
+	fmt.Println()
+	offerBashCompletion(currentUser.HomeDir)
 	return nil
 }
 
+// printServiceStatusHint prints how to check the service status for the init system
+func printServiceStatusHint(initSystem string) {
+	switch initSystem {
+	case initSystemOpenRC:
+		fmt.Println("Use 'rc-service sathub-client status' to check service status")
+	case initSystemLaunchd:
+		fmt.Printf("Use 'launchctl list %s' to check service status\n", launchdLabel)
+	default:
+		fmt.Println("Use 'systemctl --user status sathub-client' to check service status")
+	}
+}
+
+// createService writes the service definition for the init system
+func createService(initSystem, servicePath, binaryPath, configFilePath string) error {
+	switch initSystem {
+	case initSystemOpenRC:
+		return createOpenRCService(servicePath, binaryPath, configFilePath)
+	case initSystemLaunchd:
+		return createLaunchdService(servicePath, binaryPath, configFilePath)
+	default:
+		return createSystemdService(servicePath, binaryPath)
+	}
+}
+
+// startService enables and starts (or restarts) the service for the init system
+func startService(initSystem string, serviceExists bool) error {
+	switch initSystem {
+	case initSystemOpenRC:
+		return enableAndStartServiceOpenRC(serviceExists)
+	case initSystemLaunchd:
+		return loadLaunchdService(serviceExists)
+	default:
+		return enableAndStartService(serviceExists)
+	}
+}
+
+// restartService restarts an existing service so it picks up an updated binary
+func restartService(initSystem string) {
+	switch initSystem {
+	case initSystemOpenRC:
+		if err := exec.Command("rc-service", "sathub-client", "restart").Run(); err != nil {
+			fmt.Printf("Warning: failed to restart service: %v\n", err)
+			fmt.Println("You may need to run: rc-service sathub-client restart")
+		} else {
+			fmt.Println("Service restarted successfully with updated binary.")
+		}
+		return
+	case initSystemLaunchd:
+		if err := loadLaunchdService(true); err != nil {
+			fmt.Printf("Warning: failed to restart service: %v\n", err)
+		}
+		return
+	}
+
+	// Reload systemd user daemon (in case binary was updated)
+	if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
+		fmt.Printf("Warning: failed to reload systemd: %v\n", err)
+	}
+
+	// Try to restart the service
+	if err := exec.Command("systemctl", "--user", "restart", "sathub-client").Run(); err != nil {
+		fmt.Printf("Warning: failed to restart service: %v\n", err)
+		fmt.Println("You may need to run: systemctl --user restart sathub-client")
+	} else {
+		fmt.Println("Service restarted successfully with updated binary.")
+	}
+}
+
 // createSystemdService creates the systemd service file
 func createSystemdService(servicePath, binaryPath string) error {
 	serviceContent := fmt.Sprintf(`[Unit]
@@ -511,10 +1370,14 @@ Description=SatHub Data Client v2
 After=network.target
 
 [Service]
-Type=simple
+Type=notify
 ExecStart=%s
+ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=10
+WatchdogSec=60
+TimeoutStopSec=150
+StandardOutput=journal
 
 [Install]
 WantedBy=default.target
@@ -613,6 +1476,15 @@ func uninstallService() error {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
 
+	// Remove the OpenRC service if that is what was installed
+	if _, err := os.Stat(openRCServicePath); err == nil {
+		return uninstallServiceOpenRC()
+	}
+
+	if runtime.GOOS == "darwin" {
+		return uninstallServiceLaunchd(currentUser.HomeDir)
+	}
+
 	// Use user systemd directory
 	systemdUserDir := filepath.Join(currentUser.HomeDir, ".config", "systemd", "user")
 	servicePath := filepath.Join(systemdUserDir, "sathub-client.service")
@@ -658,7 +1530,10 @@ func uninstallService() error {
 	return nil
 }
 
-// copyFile copies a file from src to dst
+// copyFileBufferSize is the buffer used by copyFile, larger than the io.Copy default for big binaries
+const copyFileBufferSize = 32 * 1024
+
+// copyFile copies a file from src to dst with the permissions of src and flushes it to disk before returning
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
@@ -666,57 +1541,166 @@ func copyFile(src, dst string) error {
 	}
 	defer sourceFile.Close()
 
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return err
+	}
+
 	destFile, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	if _, err := io.CopyBuffer(destFile, sourceFile, make([]byte, copyFileBufferSize)); err != nil {
+		destFile.Close()
+		return err
+	}
+	// The umask applies when creating dst, and an existing dst keeps its mode
+	if err := destFile.Chmod(info.Mode().Perm()); err != nil {
+		destFile.Close()
+		return err
+	}
+	// A crash must not leave a partially written binary behind
+	if err := destFile.Sync(); err != nil {
+		destFile.Close()
+		return err
+	}
+	return destFile.Close()
 }
 
-// compareVersions compares two version strings (returns -1, 0, 1)
+// compareVersions compares two version strings (returns -1, 0, 1).
+// A release is newer than its pre-releases, e.g. 1.2.3 > 1.2.3-beta.1.
 func compareVersions(v1, v2 string) int {
-	parts1 := strings.Split(v1, ".")
-	parts2 := strings.Split(v2, ".")
+	// Unparsable versions compare like 0.0.0
+	major1, minor1, patch1, pre1, _ := ParseSemver(v1)
+	major2, minor2, patch2, pre2, _ := ParseSemver(v2)
 
-	maxLen := len(parts1)
-	if len(parts2) > maxLen {
-		maxLen = len(parts2)
+	for _, pair := range [][2]int{{major1, major2}, {minor1, minor2}, {patch1, patch2}} {
+		if pair[0] < pair[1] {
+			return -1
+		} else if pair[0] > pair[1] {
+			return 1
+		}
 	}
 
-	for i := 0; i < maxLen; i++ {
-		var n1, n2 int
+	switch {
+	case pre1 == pre2:
+		return 0
+	case pre1 == "":
+		return 1
+	case pre2 == "":
+		return -1
+	}
+	return comparePrerelease(pre1, pre2)
+}
 
-		if i < len(parts1) {
-			n1, _ = strconv.Atoi(parts1[i])
+// ParseSemver splits a version such as "v1.2.3-beta.1+build.5" into its numeric parts and pre-release.
+// A leading "v" and build metadata are ignored, missing minor and patch numbers are 0.
+func ParseSemver(v string) (major, minor, patch int, prerelease string, err error) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	core := v
+	if i := strings.Index(v, "-"); i >= 0 {
+		core, prerelease = v[:i], v[i+1:]
+		if prerelease == "" {
+			return 0, 0, 0, "", fmt.Errorf("invalid version %q: empty pre-release", v)
 		}
-		if i < len(parts2) {
-			n2, _ = strconv.Atoi(parts2[i])
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return 0, 0, 0, "", fmt.Errorf("invalid version %q: too many parts", v)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, 0, 0, "", fmt.Errorf("invalid version %q: %q is not a number", v, part)
 		}
+		numbers[i] = n
+	}
+	return numbers[0], numbers[1], numbers[2], prerelease, nil
+}
 
-		if n1 < n2 {
+// comparePrerelease compares two pre-release strings by their dot-separated identifiers like semver does:
+// numeric identifiers numerically and below alphanumeric ones, and a shorter list below a longer one it prefixes
+func comparePrerelease(pre1, pre2 string) int {
+	ids1 := strings.Split(pre1, ".")
+	ids2 := strings.Split(pre2, ".")
+
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		n1, err1 := strconv.Atoi(ids1[i])
+		n2, err2 := strconv.Atoi(ids2[i])
+		switch {
+		case err1 == nil && err2 == nil:
+			if n1 != n2 {
+				if n1 < n2 {
+					return -1
+				}
+				return 1
+			}
+		case err1 == nil:
 			return -1
-		} else if n1 > n2 {
+		case err2 == nil:
 			return 1
+		default:
+			if c := strings.Compare(ids1[i], ids2[i]); c != 0 {
+				return c
+			}
 		}
 	}
 
+	switch {
+	case len(ids1) < len(ids2):
+		return -1
+	case len(ids1) > len(ids2):
+		return 1
+	}
 	return 0
 }
 
-// maskToken masks a token for display (shows first 8 and last 4 characters)
+// maskToken masks a token for display. Tokens longer than 12 characters show their first 8 and last 4
+// characters and tokens of 4 to 7 characters their last 2, other tokens are masked completely.
 func maskToken(token string) string {
-	if len(token) <= 12 {
+	switch {
+	case len(token) < 4:
+		return MaskTokenCustom(token, 0, 0)
+	case len(token) < 8:
+		return MaskTokenCustom(token, 0, 2)
+	case len(token) <= 12:
+		return MaskTokenCustom(token, 0, 0)
+	}
+	return MaskTokenCustom(token, 8, 4)
+}
+
+// MaskTokenCustom replaces all but the first revealFirst and last revealLast characters of token with
+// asterisks. A token that would be revealed completely is masked entirely.
+func MaskTokenCustom(token string, revealFirst, revealLast int) string {
+	if revealFirst < 0 {
+		revealFirst = 0
+	}
+	if revealLast < 0 {
+		revealLast = 0
+	}
+	if revealFirst+revealLast >= len(token) {
 		return strings.Repeat("*", len(token))
 	}
-	return token[:8] + strings.Repeat("*", len(token)-12) + token[len(token)-4:]
+	return token[:revealFirst] + strings.Repeat("*", len(token)-revealFirst-revealLast) + token[len(token)-revealLast:]
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, errUpdateAvailable) {
+			os.Exit(1)
+		}
+		PrintError(err)
+		// A failed update signature or an aborted prompt gets its own exit code so scripts can tell it apart
+		var sigErr *SignatureError
+		if errors.As(err, &sigErr) || errors.Is(err, errAborted) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
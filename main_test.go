@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaskToken(t *testing.T) {
+	long := strings.Repeat("0123456789abcdef", 4)
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"length 1", "a", "*"},
+		{"length 4", "abcd", "**cd"},
+		{"length 7", "abcdefg", "*****fg"},
+		{"length 8", "abcdefgh", "********"},
+		{"length 12", "abcdefghijkl", "************"},
+		{"length 13", "abcdefghijklm", "abcdefgh*jklm"},
+		{"length 64", long, "01234567" + strings.Repeat("*", 52) + "cdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maskToken(tt.token)
+			if got != tt.want {
+				t.Errorf("maskToken(%q) = %q, want %q", tt.token, got, tt.want)
+			}
+			if len(got) != len(tt.token) {
+				t.Errorf("maskToken(%q) changed the length to %d", tt.token, len(got))
+			}
+		})
+	}
+}
+
+func TestMaskTokenCustom(t *testing.T) {
+	tests := []struct {
+		name                    string
+		token                   string
+		revealFirst, revealLast int
+		want                    string
+	}{
+		{"reveal both ends", "abcdefghij", 2, 3, "ab*****hij"},
+		{"reveal nothing", "abcdef", 0, 0, "******"},
+		{"negative first", "abcdef", -5, 2, "****ef"},
+		{"negative last", "abcdef", 2, -1, "ab****"},
+		{"both negative", "abcdef", -1, -1, "******"},
+		{"oversized first", "abcdef", 10, 0, "******"},
+		{"oversized last", "abcdef", 0, 10, "******"},
+		{"reveal exactly all", "abcdef", 3, 3, "******"},
+		{"empty token", "", 4, 4, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskTokenCustom(tt.token, tt.revealFirst, tt.revealLast); got != tt.want {
+				t.Errorf("MaskTokenCustom(%q, %d, %d) = %q, want %q", tt.token, tt.revealFirst, tt.revealLast, got, tt.want)
+			}
+		})
+	}
+}
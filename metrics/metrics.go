@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Upload file types used as the "type" label on upload metrics
+const (
+	UploadTypeImage = "image"
+	UploadTypeCBOR  = "cbor"
+	UploadTypeCADU  = "cadu"
+	UploadTypeIQ    = "iq"
+)
+
+// Collector records client observations and exposes them in the Prometheus text format.
+// All methods are safe to call on a nil Collector, in which case they do nothing.
+type Collector struct {
+	registry           *prometheus.Registry
+	passesProcessed    *prometheus.CounterVec
+	passesFailed       prometheus.Counter
+	uploadDuration     *prometheus.HistogramVec
+	websocketConnected prometheus.Gauge
+	healthCheckErrors  prometheus.Counter
+}
+
+// NewCollector creates a collector with all client metrics registered
+func NewCollector() *Collector {
+	c := &Collector{
+		registry: prometheus.NewRegistry(),
+		passesProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sathub_passes_processed_total",
+			Help: "Total number of satellite passes uploaded successfully.",
+		}, []string{"satellite"}),
+		passesFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sathub_passes_failed_total",
+			Help: "Total number of satellite passes that failed to process.",
+		}),
+		uploadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sathub_upload_duration_seconds",
+			Help:    "Duration of file uploads to the SatHub API.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{"type"}),
+		websocketConnected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sathub_websocket_connected",
+			Help: "Whether the WebSocket connection to the server is established (1) or not (0).",
+		}),
+		healthCheckErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sathub_health_check_errors_total",
+			Help: "Total number of failed station health checks.",
+		}),
+	}
+
+	c.registry.MustRegister(
+		c.passesProcessed,
+		c.passesFailed,
+		c.uploadDuration,
+		c.websocketConnected,
+		c.healthCheckErrors,
+	)
+
+	return c
+}
+
+// ListenAndServe serves the /metrics endpoint on addr and blocks until the server fails
+func (c *Collector) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// PassProcessed records a successfully processed satellite pass
+func (c *Collector) PassProcessed(satellite string) {
+	if c == nil {
+		return
+	}
+	c.passesProcessed.WithLabelValues(satellite).Inc()
+}
+
+// PassFailed records a satellite pass that failed to process
+func (c *Collector) PassFailed() {
+	if c == nil {
+		return
+	}
+	c.passesFailed.Inc()
+}
+
+// ObserveUpload records the duration of a single file upload
+func (c *Collector) ObserveUpload(fileType string, duration time.Duration) {
+	if c == nil {
+		return
+	}
+	c.uploadDuration.WithLabelValues(fileType).Observe(duration.Seconds())
+}
+
+// SetWebSocketConnected records the current WebSocket connection state
+func (c *Collector) SetWebSocketConnected(connected bool) {
+	if c == nil {
+		return
+	}
+	if connected {
+		c.websocketConnected.Set(1)
+	} else {
+		c.websocketConnected.Set(0)
+	}
+}
+
+// HealthCheckError records a failed station health check
+func (c *Collector) HealthCheckError() {
+	if c == nil {
+		return
+	}
+	c.healthCheckErrors.Inc()
+}
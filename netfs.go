@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// networkFilesystem returns the name of the network filesystem path is on, only detected on Linux
+func networkFilesystem(path string) string {
+	return ""
+}
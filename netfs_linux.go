@@ -0,0 +1,26 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+// Filesystem magic numbers from statfs(2) of the network filesystems inotify doesn't work on
+var networkFilesystemTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x5346414f: "afs",
+	0x73757245: "coda",
+	0x01021997: "9p",
+}
+
+// networkFilesystem returns the name of the network filesystem path is on, or "" for local filesystems
+// and when it can't be determined
+func networkFilesystem(path string) string {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return ""
+	}
+	return networkFilesystemTypes[uint32(stat.Type)]
+}
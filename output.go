@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonOutput is set by the --json flag to emit machine-readable output
+var jsonOutput bool
+
+// jsonResponse is the envelope written to stdout for --json output
+type jsonResponse struct {
+	OK   bool        `json:"ok"`
+	Data interface{} `json:"data"`
+}
+
+// jsonError is written to stderr for --json output when a command fails
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+// PrintJSON writes a successful result to stdout as a single JSON object
+func PrintJSON(v interface{}) {
+	data, err := json.Marshal(jsonResponse{OK: true, Data: v})
+	if err != nil {
+		PrintError(fmt.Errorf("failed to marshal output: %w", err))
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+// PrintError writes an error to stderr, as JSON when --json is set
+func PrintError(err error) {
+	if !jsonOutput {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	data, marshalErr := json.Marshal(jsonError{Error: err.Error()})
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+)
+
+// logPanic logs a panic recovered in a long-running goroutine together with the stack trace where it happened.
+// It must be called from the deferred function that called recover.
+func logPanic(logger zerolog.Logger, goroutine string, r interface{}) {
+	logger.Error().
+		Str("goroutine", goroutine).
+		Interface("panic", r).
+		Str("stack", string(debug.Stack())).
+		Msg("Recovered from panic")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause processing in the running client",
+	Long:  "Stop the running client from starting new uploads until 'sathub-client resume'. Passes that arrive meanwhile stay in the watch directory.",
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendPauseCommand(controlPause, "Processing paused")
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume processing in the running client",
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendPauseCommand(controlResume, "Processing resumed")
+	},
+}
+
+// sendPauseCommand sends a pause or resume command to the running client, there is nothing to pause without one
+func sendPauseCommand(cmd, message string) error {
+	if dryRun {
+		fmt.Printf("[dry-run] Would send %s to the running client\n", cmd)
+		return nil
+	}
+
+	if err := sendControlCommand(cmd, nil); err != nil {
+		if errors.Is(err, errDaemonNotRunning) {
+			return fmt.Errorf("%w, nothing to %s", err, cmd)
+		}
+		return err
+	}
+
+	if jsonOutput {
+		PrintJSON(map[string]bool{"paused": cmd == controlPause})
+		return nil
+	}
+	fmt.Printf("✓ %s\n", message)
+	return nil
+}
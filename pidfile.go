@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"strconv"
+	"strings"
+)
+
+// pidFilePath returns the expanded PID file location from the loaded config
+func pidFilePath() string {
+	path := cfg.Options.PIDFile
+	if path == "" {
+		path = config.DefaultPIDFile
+	}
+	return config.ExpandPath(path)
+}
+
+// readPIDFile returns the PID stored in the PID file at path
+func readPIDFile(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file %s", path)
+	}
+	return pid, nil
+}
+
+// acquirePIDFile writes the PID of this process to path, refusing to when the PID file
+// belongs to another running instance unless force is set
+func acquirePIDFile(path string, force bool) error {
+	if !force {
+		if pid, err := readPIDFile(path); err == nil && pid != os.Getpid() && processAlive(pid) {
+			return fmt.Errorf("sathub-client is already running with PID %d (%s), use --force-pid if that process is not the client", pid, path)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
+
+// releasePIDFile removes the PID file at path if it still holds the PID of this process
+func releasePIDFile(path string) {
+	if pid, err := readPIDFile(path); err == nil && pid == os.Getpid() {
+		if err := os.Remove(path); err != nil {
+			logger.Warn().Err(err).Str("path", path).Msg("Failed to remove PID file")
+		}
+	}
+}
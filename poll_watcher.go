@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// dirWatcher reports changes in watched directories, implemented by inotify and by polling
+type dirWatcher interface {
+	Add(path string) error
+	Close() error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+// notifyWatcher is the inotify (or platform equivalent) backed dirWatcher
+type notifyWatcher struct {
+	watcher *fsnotify.Watcher
+}
+
+// Add starts watching path
+func (w *notifyWatcher) Add(path string) error {
+	return w.watcher.Add(path)
+}
+
+// Close removes all watches and closes the event channels
+func (w *notifyWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// Events returns the channel of file system events
+func (w *notifyWatcher) Events() <-chan fsnotify.Event {
+	return w.watcher.Events
+}
+
+// Errors returns the channel of watcher errors
+func (w *notifyWatcher) Errors() <-chan error {
+	return w.watcher.Errors
+}
+
+// pollEntry is the state of a directory entry when it was last listed
+type pollEntry struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollingWatcher lists the watched directories every interval and reports created, written and
+// removed entries like fsnotify does. It works on network filesystems where inotify sees nothing.
+type pollingWatcher struct {
+	interval  time.Duration
+	events    chan fsnotify.Event
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	dirs map[string]map[string]pollEntry // watched directory -> entry name -> state
+}
+
+// newPollingWatcher creates a polling watcher and starts scanning
+func newPollingWatcher(interval time.Duration) *pollingWatcher {
+	w := &pollingWatcher{
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]pollEntry),
+	}
+	go w.run()
+	return w
+}
+
+// Add starts watching path, its current entries are not reported as created
+func (w *pollingWatcher) Add(path string) error {
+	w.mu.Lock()
+	_, watched := w.dirs[path]
+	w.mu.Unlock()
+	if watched {
+		return nil
+	}
+
+	entries, err := listPollEntries(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.dirs[path] = entries
+	w.mu.Unlock()
+	return nil
+}
+
+// Close stops scanning, the event channels are closed once the current scan is finished
+func (w *pollingWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return nil
+}
+
+// Events returns the channel of file system events
+func (w *pollingWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors returns the channel of errors that occurred while listing a directory
+func (w *pollingWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// run scans the watched directories every interval until Close is called
+func (w *pollingWatcher) run() {
+	defer close(w.errors)
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll lists every watched directory and sends the differences to the last listing as events
+func (w *pollingWatcher) poll() {
+	w.mu.Lock()
+	dirs := make([]string, 0, len(w.dirs))
+	for dir := range w.dirs {
+		dirs = append(dirs, dir)
+	}
+	w.mu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		entries, err := listPollEntries(dir)
+		if os.IsNotExist(err) {
+			// Like inotify, a removed directory is no longer watched, its parent reports the removal
+			w.mu.Lock()
+			delete(w.dirs, dir)
+			w.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			if !w.sendError(fmt.Errorf("failed to list %s: %w", dir, err)) {
+				return
+			}
+			continue
+		}
+
+		w.mu.Lock()
+		previous, watched := w.dirs[dir]
+		if watched {
+			w.dirs[dir] = entries
+		}
+		w.mu.Unlock()
+		if !watched {
+			continue
+		}
+
+		// Don't hold the lock while sending, the receiver may call Add
+		for _, event := range diffPollEntries(dir, previous, entries) {
+			if !w.sendEvent(event) {
+				return
+			}
+		}
+	}
+}
+
+// sendEvent delivers event unless the watcher is closed first
+func (w *pollingWatcher) sendEvent(event fsnotify.Event) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// sendError delivers err unless the watcher is closed first
+func (w *pollingWatcher) sendError(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// listPollEntries returns the state of the entries in dir
+func listPollEntries(dir string) (map[string]pollEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]pollEntry, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil {
+			// Removed since ReadDir, the next scan reports it if it was known
+			continue
+		}
+		entries[dirEntry.Name()] = pollEntry{modTime: info.ModTime(), size: info.Size()}
+	}
+	return entries, nil
+}
+
+// diffPollEntries returns the events that turn the previous listing of dir into the current one, sorted by name
+func diffPollEntries(dir string, previous, current map[string]pollEntry) []fsnotify.Event {
+	var events []fsnotify.Event
+	for name, entry := range current {
+		path := filepath.Join(dir, name)
+		old, existed := previous[name]
+		switch {
+		case !existed:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !entry.modTime.Equal(old.modTime) || entry.size != old.size:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	for name := range previous {
+		if _, exists := current[name]; !exists {
+			events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return events
+}
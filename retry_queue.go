@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Steps of processing a pass, recorded as the failed step in the retry queue
+const (
+	stepPrepare       = "prepare"
+	stepPreUploadHook = "pre_upload_hook"
+	stepCreatePost    = "create_post"
+	stepUploadCADU    = "upload_cadu"
+	stepUploadIQ      = "upload_iq"
+	stepUploadCBOR    = "upload_cbor"
+	stepUploadImages  = "upload_images"
+	stepTimedOut      = "timed_out" // the pass never became complete
+)
+
+// RetryEntry describes a pass that failed to process and will be retried
+type RetryEntry struct {
+	Path         string         `json:"path"`
+	PostID       string         `json:"post_id,omitempty"` // Post created by an earlier attempt, reused on retry
+	FailedStep   string         `json:"failed_step"`
+	AttemptCount int            `json:"attempt_count"`
+	LastAttempt  time.Time      `json:"last_attempt"`
+	LastError    string         `json:"last_error,omitempty"`
+	Uploaded     []string       `json:"uploaded,omitempty"` // Files already attached to the post, relative to Path
+	History      []RetryAttempt `json:"history,omitempty"`
+}
+
+// RetryAttempt is one failed attempt of a pass
+type RetryAttempt struct {
+	Time  time.Time `json:"time"`
+	Step  string    `json:"step"`
+	Error string    `json:"error"`
+}
+
+// hasUploaded reports whether file (relative to the pass directory) was uploaded by an earlier attempt
+func (e *RetryEntry) hasUploaded(file string) bool {
+	for _, uploaded := range e.Uploaded {
+		if uploaded == file {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryQueue persists the passes that failed to process, keyed by directory path,
+// so a retry after a restart can resume where the previous attempt stopped
+type RetryQueue struct {
+	store *FileStore
+}
+
+// NewRetryQueue opens the retry queue at path
+func NewRetryQueue(path string) (*RetryQueue, error) {
+	store, err := NewFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RetryQueue{store: store}, nil
+}
+
+// Get returns the queued entry for dirPath
+func (q *RetryQueue) Get(dirPath string) (*RetryEntry, bool) {
+	value, ok := q.store.Get(dirPath)
+	if !ok {
+		return nil, false
+	}
+	var entry RetryEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Save adds or replaces the entry for its path
+func (q *RetryQueue) Save(entry *RetryEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retry entry: %w", err)
+	}
+	return q.store.Set(entry.Path, string(value))
+}
+
+// Remove drops the entry for dirPath
+func (q *RetryQueue) Remove(dirPath string) error {
+	return q.store.Delete(dirPath)
+}
+
+// Entries returns all queued entries, oldest attempt first
+func (q *RetryQueue) Entries() []RetryEntry {
+	var entries []RetryEntry
+	for _, value := range q.store.All() {
+		var entry RetryEntry
+		if err := json.Unmarshal([]byte(value), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAttempt.Before(entries[j].LastAttempt)
+	})
+	return entries
+}
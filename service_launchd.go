@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// launchdLabel is the launchd job label and plist file name for the service
+const launchdLabel = "de.sathub.client"
+
+// launchdLogPath returns where the launchd service writes its output
+func launchdLogPath(homeDir string) string {
+	return filepath.Join(homeDir, "Library", "Logs", "sathub-client.log")
+}
+
+// createLaunchdService creates the launchd agent plist
+func createLaunchdService(servicePath, binaryPath, configFilePath string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	logPath := launchdLogPath(homeDir)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	serviceContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>--config</string>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, launchdLabel, binaryPath, configFilePath, logPath, logPath)
+
+	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
+		return fmt.Errorf("failed to write service file: %w", err)
+	}
+
+	return nil
+}
+
+// launchdPlistPath returns the path of the launchd agent plist
+func launchdPlistPath(homeDir string) string {
+	return filepath.Join(homeDir, "Library", "LaunchAgents", launchdLabel+".plist")
+}
+
+// loadLaunchdService loads the launchd agent, unloading it first when it already exists
+func loadLaunchdService(serviceExists bool) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	plistPath := launchdPlistPath(homeDir)
+
+	if serviceExists {
+		fmt.Println("Restarting service with new configuration...")
+		// Ignore errors if the agent is not currently loaded
+		exec.Command("launchctl", "unload", plistPath).Run()
+	} else {
+		fmt.Println("Starting service...")
+	}
+
+	if err := exec.Command("launchctl", "load", "-w", plistPath).Run(); err != nil {
+		fmt.Printf("Warning: Failed to start service: %v\n", err)
+		fmt.Printf("You can manually start it with: launchctl load -w %s\n", plistPath)
+	} else {
+		fmt.Println("✓ Service started successfully!")
+	}
+
+	return nil
+}
+
+// uninstallServiceLaunchd unloads the launchd agent and removes its plist
+func uninstallServiceLaunchd(homeDir string) error {
+	plistPath := launchdPlistPath(homeDir)
+
+	// Check if service exists
+	if _, err := os.Stat(plistPath); os.IsNotExist(err) {
+		fmt.Println("Service is not installed.")
+		return nil
+	}
+
+	fmt.Println("Uninstalling sathub-client service...")
+
+	// Stop and unload the agent (ignore errors if it's not loaded)
+	fmt.Println("Stopping service...")
+	exec.Command("launchctl", "unload", "-w", plistPath).Run()
+
+	// Remove the plist
+	fmt.Println("Removing service file...")
+	if err := os.Remove(plistPath); err != nil {
+		return fmt.Errorf("failed to remove service file: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Service uninstalled successfully!")
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Supported init systems for install-service
+const (
+	initSystemSystemd = "systemd"
+	initSystemOpenRC  = "openrc"
+	initSystemLaunchd = "launchd"
+)
+
+const (
+	// openRCServicePath is where the OpenRC service script is installed
+	openRCServicePath = "/etc/init.d/sathub-client"
+
+	// openRCLogPath is where the OpenRC service writes its output
+	openRCLogPath = "/var/log/sathub-client.log"
+)
+
+// detectInitSystem returns the init system of the host, defaulting to systemd
+func detectInitSystem() string {
+	if runtime.GOOS == "darwin" {
+		return initSystemLaunchd
+	}
+	if _, err := os.Stat("/run/openrc"); err == nil {
+		return initSystemOpenRC
+	}
+	if _, err := os.Stat("/sbin/openrc-run"); err == nil {
+		return initSystemOpenRC
+	}
+	return initSystemSystemd
+}
+
+// createOpenRCService creates the OpenRC service script
+func createOpenRCService(servicePath, binaryPath, configFilePath string) error {
+	serviceContent := fmt.Sprintf(`#!/sbin/openrc-run
+
+name="sathub-client"
+description="SatHub Data Client"
+command="%s"
+command_args="--config %s"
+command_background=true
+pidfile="/run/${RC_SVCNAME}.pid"
+output_log="%s"
+error_log="%s"
+
+depend() {
+	need net
+	after firewall
+}
+
+reload() {
+	ebegin "Reloading ${RC_SVCNAME} configuration"
+	start-stop-daemon --signal HUP --pidfile "${pidfile}"
+	eend $?
+}
+`, binaryPath, configFilePath, openRCLogPath, openRCLogPath)
+
+	if err := os.WriteFile(servicePath, []byte(serviceContent), 0755); err != nil {
+		return fmt.Errorf("failed to write service file: %w", err)
+	}
+
+	return nil
+}
+
+// enableAndStartServiceOpenRC adds the OpenRC service to the default runlevel and starts it
+func enableAndStartServiceOpenRC(serviceExists bool) error {
+	// Add to default runlevel
+	if err := exec.Command("rc-update", "add", "sathub-client", "default").Run(); err != nil {
+		return fmt.Errorf("failed to enable service: %w", err)
+	}
+
+	// Start or restart the service
+	var startCmd *exec.Cmd
+	if serviceExists {
+		fmt.Println("Restarting service with new configuration...")
+		startCmd = exec.Command("rc-service", "sathub-client", "restart")
+	} else {
+		fmt.Println("Starting service...")
+		startCmd = exec.Command("rc-service", "sathub-client", "start")
+	}
+
+	if err := startCmd.Run(); err != nil {
+		fmt.Printf("Warning: Failed to start service: %v\n", err)
+		fmt.Println("You can manually start it with: rc-service sathub-client start")
+	} else {
+		fmt.Println("✓ Service started successfully!")
+	}
+
+	return nil
+}
+
+// uninstallServiceOpenRC stops the OpenRC service and removes its script
+func uninstallServiceOpenRC() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("removing an OpenRC service requires root, run: sudo sathub-client uninstall-service")
+	}
+
+	fmt.Println("Uninstalling sathub-client service...")
+
+	// Stop the service (ignore errors if it's not running)
+	fmt.Println("Stopping service...")
+	exec.Command("rc-service", "sathub-client", "stop").Run()
+
+	// Remove from the default runlevel (ignore errors if it's not enabled)
+	fmt.Println("Disabling service...")
+	exec.Command("rc-update", "del", "sathub-client", "default").Run()
+
+	// Remove the service script
+	fmt.Println("Removing service file...")
+	if err := os.Remove(openRCServicePath); err != nil {
+		return fmt.Errorf("failed to remove service file: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Service uninstalled successfully!")
+	return nil
+}
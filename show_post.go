@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var showPostCmd = &cobra.Command{
+	Use:   "show-post <post-id>",
+	Short: "Show the details of an uploaded post",
+	Long:  "Fetch a post from the API and print its satellite, timestamp, metadata and attached files, to verify an upload without opening the web UI.",
+	Args:  cobra.ExactArgs(1),
+	// Complete with the recent posts of the station
+	ValidArgsFunction: completePostID,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiClient, err := newAPIClient(cfg, cfg.PrimaryStation())
+		if err != nil {
+			return err
+		}
+
+		post, err := apiClient.GetPost(context.Background(), args[0])
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(post)
+			return nil
+		}
+
+		printPost(post)
+		return nil
+	},
+}
+
+// printPost prints a post for humans
+func printPost(post *PostResponse) {
+	yesNo := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Printf("ID:         %s\n", post.ID)
+	fmt.Printf("Satellite:  %s\n", post.SatelliteName)
+	fmt.Printf("Timestamp:  %s\n", post.Timestamp)
+	fmt.Printf("Station:    %s (%s)\n", post.StationName, post.StationID)
+	if post.NORAD != 0 {
+		fmt.Printf("NORAD:      %d\n", post.NORAD)
+	}
+	if post.FrequencyMHz != 0 {
+		fmt.Printf("Frequency:  %.3f MHz\n", post.FrequencyMHz)
+	}
+	fmt.Printf("CBOR:       %s\n", yesNo(post.HasCBOR))
+	fmt.Printf("CADU:       %s\n", yesNo(post.HasCADU))
+	fmt.Printf("Created:    %s\n", post.CreatedAt)
+	fmt.Printf("Images:     %d\n", len(post.Images))
+	for _, image := range post.Images {
+		fmt.Printf("  - %s\n", image.Filename)
+	}
+
+	if post.Metadata != "" {
+		fmt.Println()
+		fmt.Println("Metadata:")
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, []byte(post.Metadata), "  ", "  "); err == nil {
+			fmt.Printf("  %s\n", pretty.String())
+		} else {
+			fmt.Printf("  %s\n", post.Metadata)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// passesSchema creates the pass history table
+const passesSchema = `CREATE TABLE IF NOT EXISTS passes (
+	id TEXT PRIMARY KEY,
+	path TEXT,
+	satellite TEXT,
+	post_id TEXT,
+	processed_at DATETIME,
+	duration_ms INTEGER,
+	image_count INTEGER,
+	success BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS passes_processed_at ON passes (processed_at);`
+
+// SQLiteStore is a ProcessedStore backed by an SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the pass history database at path, creating it if it doesn't exist
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// The pragmas are applied to every pooled connection, the busy timeout first so
+	// watchers of several stations can open the database at the same time.
+	// WAL lets the history command read while the daemon is writing.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+
+	if _, err := db.Exec(passesSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// RecordPass stores a processed pass, generating an ID if it has none
+func (s *SQLiteStore) RecordPass(record PassRecord) error {
+	if record.ID == "" {
+		record.ID = newPassID()
+	}
+
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO passes (id, path, satellite, post_id, processed_at, duration_ms, image_count, success)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, record.Path, record.Satellite, record.PostID,
+		record.ProcessedAt.UTC(), record.DurationMS, record.ImageCount, record.Success,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record pass: %w", err)
+	}
+	return nil
+}
+
+// QueryPasses returns the passes matching filter, newest first
+func (s *SQLiteStore) QueryPasses(filter PassFilter) ([]PassRecord, error) {
+	query := "SELECT id, path, satellite, post_id, processed_at, duration_ms, image_count, success FROM passes"
+	var conditions []string
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "processed_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Satellite != "" {
+		conditions = append(conditions, "satellite = ? COLLATE NOCASE")
+		args = append(args, filter.Satellite)
+	}
+	if filter.FailedOnly {
+		conditions = append(conditions, "NOT success")
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY processed_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query passes: %w", err)
+	}
+	defer rows.Close()
+
+	var records []PassRecord
+	for rows.Next() {
+		var record PassRecord
+		if err := rows.Scan(&record.ID, &record.Path, &record.Satellite, &record.PostID,
+			&record.ProcessedAt, &record.DurationMS, &record.ImageCount, &record.Success); err != nil {
+			return nil, fmt.Errorf("failed to read pass: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read passes: %w", err)
+	}
+	return records, nil
+}
+
+// CountPasses returns the number of successful or failed passes stored below one of pathPrefixes.
+// The prefixes are compared with substr instead of LIKE, which ignores case and treats _ as a wildcard.
+func (s *SQLiteStore) CountPasses(success bool, pathPrefixes []string) (int, error) {
+	if len(pathPrefixes) == 0 {
+		return 0, nil
+	}
+
+	conditions := make([]string, 0, len(pathPrefixes))
+	args := []interface{}{success}
+	for _, prefix := range pathPrefixes {
+		if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+			prefix += string(filepath.Separator)
+		}
+		conditions = append(conditions, "substr(path, 1, length(?)) = ?")
+		args = append(args, prefix, prefix)
+	}
+	query := "SELECT COUNT(*) FROM passes WHERE success = ? AND (" + strings.Join(conditions, " OR ") + ")"
+
+	var count int
+	if err := s.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count passes: %w", err)
+	}
+	return count, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// newPassID returns a random ID for a pass history entry
+func newPassID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
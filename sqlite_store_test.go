@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStoreCountPasses(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	for _, record := range []PassRecord{
+		{Path: "/data/noaa_passes/pass1", Success: true},
+		{Path: "/data/noaa_passes/pass2", Success: true},
+		{Path: "/data/noaa_passes/pass3", Success: false},
+		{Path: "/data/noaa_passes/nested/pass4", Success: true},
+		{Path: "/data/noaaXpasses/pass5", Success: true},
+		{Path: "/data/NOAA_passes/pass6", Success: true},
+		{Path: "/data/noaa_passes_old/pass7", Success: true},
+		{Path: "/data/meteor/pass8", Success: true},
+	} {
+		record.ProcessedAt = time.Now()
+		if err := store.RecordPass(record); err != nil {
+			t.Fatalf("failed to record pass: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		success  bool
+		prefixes []string
+		want     int
+	}{
+		{"successful below one path", true, []string{"/data/noaa_passes"}, 3},
+		{"trailing separator", true, []string{"/data/noaa_passes/"}, 3},
+		{"failed", false, []string{"/data/noaa_passes"}, 1},
+		{"several paths", true, []string{"/data/noaa_passes", "/data/meteor"}, 4},
+		{"overlapping paths count once", true, []string{"/data/noaa_passes", "/data/noaa_passes/nested"}, 3},
+		{"no paths", true, nil, 0},
+		{"unknown path", true, []string{"/srv"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.CountPasses(tt.success, tt.prefixes)
+			if err != nil {
+				t.Fatalf("CountPasses failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountPasses(%v, %q) = %d, want %d", tt.success, tt.prefixes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sathub-client/config"
+	"sathub-client/metrics"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// StationContext bundles the clients and watcher of one station run by the daemon
+type StationContext struct {
+	Station       config.StationConfig
+	APIClient     *APIClient
+	WSClient      *WSClient
+	Watcher       *FileWatcher
+	WatcherConfig *Config
+
+	logger         zerolog.Logger
+	healthFailures int // Consecutive failed health checks
+}
+
+// multiStation reports whether the config lists several stations instead of the single station section
+func multiStation() bool {
+	return len(cfg.Stations) > 0
+}
+
+// newStationContext checks the connection of station and creates its watcher and WebSocket client without starting them
+func newStationContext(station config.StationConfig, collector *metrics.Collector) (*StationContext, error) {
+	apiClient, err := newAPIClient(cfg, station)
+	if err != nil {
+		return nil, err
+	}
+
+	healthResp, err := apiClient.StationHealth(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("initial health check failed: %w", err)
+	}
+	station.ID = healthResp.StationID
+	// Server access logs can tell stations apart from here on, nothing else uses the client yet
+	apiClient.StationID = station.ID
+
+	sc := &StationContext{
+		Station:   station,
+		APIClient: apiClient,
+		logger:    logger.With().Str("station_id", station.ID).Logger(),
+	}
+	sc.logger.Info().
+		Str("api_url", station.APIURL).
+		Str("watch_path", station.Watch).
+		Str("processed_dir", station.Processed).
+		Msg("Connected to station")
+
+	sc.WatcherConfig = newStationWatcherConfig(station)
+	if multiStation() {
+		sc.WatcherConfig.StationID = station.ID
+	}
+	sc.WatcherConfig.UpdateFromServerSettings(healthResp.Settings)
+
+	sc.Watcher, err = NewFileWatcher(sc.WatcherConfig, apiClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	sc.Watcher.SetMetrics(collector)
+	apiClient.SetWatcher(sc.Watcher)
+
+	sc.WSClient = NewWSClient(cfg, configPath, station.ID)
+	sc.WSClient.SetStation(station)
+	sc.WSClient.MaxReconnectAttempts = cfg.Options.WSMaxReconnectAttempts
+	sc.WSClient.SetMetrics(collector)
+	sc.WSClient.SetAPIClient(apiClient)
+	sc.WSClient.SetWatcher(sc.Watcher)
+
+	sc.Watcher.SetOnDiskSpaceLow(func(freeMB int64) {
+		sc.WSClient.SendStatusError(fmt.Sprintf("low disk space on watch partition: %d MB free", freeMB))
+	})
+	sc.WSClient.SetOnForceScan(func() {
+		sc.logger.Info().Msg("Received force scan command from server")
+		// Scan in the background so the WebSocket read loop is not blocked
+		go sc.Watcher.processExistingDirectories()
+	})
+	sc.WSClient.SetOnPause(sc.Watcher.Pause)
+	sc.WSClient.SetOnResume(sc.Watcher.Resume)
+
+	return sc, nil
+}
+
+// newStationContexts creates the contexts of all configured stations concurrently
+func newStationContexts(collector *metrics.Collector) ([]*StationContext, error) {
+	stations := cfg.StationConfigs()
+	contexts := make([]*StationContext, len(stations))
+	errs := make([]error, len(stations))
+
+	var wg sync.WaitGroup
+	for i, station := range stations {
+		wg.Add(1)
+		go func(i int, station config.StationConfig) {
+			defer wg.Done()
+			contexts[i], errs[i] = newStationContext(station, collector)
+		}(i, station)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			// Release the watchers that were created before giving up
+			for _, sc := range contexts {
+				if sc != nil {
+					sc.Watcher.Stop()
+				}
+			}
+			if multiStation() {
+				return nil, fmt.Errorf("station %d (%s): %w", i+1, stations[i].Watch, err)
+			}
+			return nil, err
+		}
+	}
+	return contexts, nil
+}
+
+// checkHealth runs a health check with one retry and applies the server settings, returning whether it succeeded
+func (sc *StationContext) checkHealth(collector *metrics.Collector) bool {
+	healthResp, err := sc.APIClient.StationHealth(context.Background())
+	if err != nil {
+		// Retry once after a brief delay
+		time.Sleep(1 * time.Second)
+		healthResp, err = sc.APIClient.StationHealth(context.Background())
+		if err != nil {
+			sc.healthFailures++
+			sc.logger.Warn().Err(err).Int("consecutive_failures", sc.healthFailures).Msg("Health check failed after retry")
+			collector.HealthCheckError()
+			if sc.healthFailures == maxHealthCheckFailures {
+				sc.logger.Error().Msg("Too many consecutive health check failures, no longer notifying systemd watchdog")
+			}
+			return false
+		}
+	}
+	sc.healthFailures = 0
+
+	// Update config with server settings
+	sc.WatcherConfig.UpdateFromServerSettings(healthResp.Settings)
+	sc.logger.Info().Msg("Health check successful")
+	return true
+}
+
+// checkStationsHealth runs the health checks of all stations concurrently, returning whether all succeeded
+func checkStationsHealth(stations []*StationContext, collector *metrics.Collector) bool {
+	healthy := make([]bool, len(stations))
+	var wg sync.WaitGroup
+	for i, sc := range stations {
+		wg.Add(1)
+		go func(i int, sc *StationContext) {
+			defer wg.Done()
+			healthy[i] = sc.checkHealth(collector)
+		}(i, sc)
+	}
+	wg.Wait()
+
+	for _, ok := range healthy {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// healthCheckStalled reports whether a station failed too many health checks in a row
+func healthCheckStalled(stations []*StationContext) bool {
+	for _, sc := range stations {
+		if sc.healthFailures >= maxHealthCheckFailures {
+			return true
+		}
+	}
+	return false
+}
+
+// stopStations stops the watchers of all stations concurrently, waiting for their in-flight uploads
+func stopStations(stations []*StationContext) {
+	var wg sync.WaitGroup
+	for _, sc := range stations {
+		wg.Add(1)
+		go func(sc *StationContext) {
+			defer wg.Done()
+			sc.Watcher.Stop()
+		}(sc)
+	}
+	wg.Wait()
+}
+
+// stationDataDir returns the directory for the local state of station, a subdirectory
+// of the data directory per watch path when several stations are configured
+func stationDataDir(station config.StationConfig) string {
+	dataDir := config.ExpandPath(config.DefaultDataDir)
+	if !multiStation() {
+		return dataDir
+	}
+	watch := filepath.Clean(config.ExpandPath(station.Watch))
+	sum := sha256.Sum256([]byte(watch))
+	return filepath.Join(dataDir, "stations", filepath.Base(watch)+"-"+hex.EncodeToString(sum[:4]))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the running client",
+	Long:  "Ask the running client for its uptime and the connection and pause state of each station. When no client is running, the station is checked with a health check instead.",
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status DaemonStatus
+		err := sendControlCommand(controlStatus, &status)
+		if errors.Is(err, errDaemonNotRunning) {
+			return printOfflineStatus()
+		}
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(map[string]interface{}{"running": true, "client": status})
+			return nil
+		}
+
+		fmt.Printf("Client:    running (PID %d, version %s)\n", status.PID, status.Version)
+		fmt.Printf("Uptime:    %s\n", (time.Duration(status.UptimeSeconds) * time.Second).String())
+		for _, station := range status.Stations {
+			connection := "disconnected"
+			if station.Connected {
+				connection = "connected"
+			}
+			state := "processing"
+			if station.Paused {
+				state = "paused"
+			}
+			fmt.Printf("Station %s\n", station.StationID)
+			fmt.Printf("  Watching:  %s\n", station.WatchPath)
+			fmt.Printf("  Server:    %s\n", connection)
+			fmt.Printf("  State:     %s\n", state)
+			stats := station.Stats
+			fmt.Printf("  Passes:    %d processed, %d failed, %d skipped\n", stats.ProcessedCount, stats.FailedCount, stats.SkippedCount)
+			if stats.LastProcessedAt != nil {
+				fmt.Printf("  Last pass: %s\n", stats.LastProcessedAt.Local().Format(time.RFC3339))
+			}
+			if stats.LastFailedAt != nil {
+				fmt.Printf("  Last failure: %s (%s)\n", stats.LastFailedAt.Local().Format(time.RFC3339), stats.LastFailedError)
+			}
+		}
+		return nil
+	},
+}
+
+// printOfflineStatus reports that no client is running and checks the primary station with the API
+func printOfflineStatus() error {
+	station := cfg.PrimaryStation()
+	apiClient, err := newAPIClient(cfg, station)
+	if err != nil {
+		return err
+	}
+	healthResp, healthErr := apiClient.StationHealth(context.Background())
+
+	if jsonOutput {
+		result := map[string]interface{}{"running": false}
+		if healthErr != nil {
+			result["api_error"] = healthErr.Error()
+		} else {
+			result["station_id"] = healthResp.StationID
+			result["station_name"] = healthResp.StationName
+		}
+		PrintJSON(result)
+		return nil
+	}
+
+	fmt.Println("Client:    not running")
+	if healthErr != nil {
+		fmt.Printf("API:       unreachable (%v)\n", healthErr)
+		return nil
+	}
+	fmt.Printf("API:       station %s reachable at %s\n", healthResp.StationID, station.APIURL)
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sathub-client/config"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// minStopTimeout is the shortest time stop waits for the client to exit before killing it
+	minStopTimeout = 30 * time.Second
+
+	// stopTimeoutMargin is added to the shutdown timeout so the client can exit on its own after giving up
+	// on in-flight uploads, like TimeoutStopSec in the systemd unit
+	stopTimeoutMargin = 30 * time.Second
+
+	// stopPollInterval is how often stop checks whether the client has exited
+	stopPollInterval = 500 * time.Millisecond
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running client",
+	Long: `Send SIGTERM to the client named in the PID file and wait for it to finish in-flight uploads
+and exit. The wait is intervals.shutdown_timeout plus 30 seconds, at least 30 seconds.
+A client that does not exit in time is killed.`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stopClient()
+	},
+}
+
+// stopTimeout returns how long stop waits for the client to exit before killing it, which must be longer
+// than the client itself waits for in-flight uploads
+func stopTimeout(shutdownTimeoutSec int) time.Duration {
+	if shutdownTimeoutSec <= 0 {
+		shutdownTimeoutSec = config.DefaultShutdownTimeout
+	}
+	timeout := time.Duration(shutdownTimeoutSec)*time.Second + stopTimeoutMargin
+	if timeout < minStopTimeout {
+		return minStopTimeout
+	}
+	return timeout
+}
+
+// stopClient stops the process named in the PID file, killing it when it does not exit within stopTimeout
+func stopClient() error {
+	pidPath := pidFilePath()
+	pid, err := readPIDFile(pidPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read PID file: %w", err)
+		}
+		if pids := runningClientPIDs(); len(pids) > 0 {
+			return fmt.Errorf("no PID file at %s, but sathub-client is running with PID %s; if it runs as a service, use 'systemctl --user stop sathub-client'", pidPath, strings.Join(pids, ", "))
+		}
+		return fmt.Errorf("sathub-client is not running (no PID file at %s)", pidPath)
+	}
+
+	if !processAlive(pid) {
+		if !dryRun {
+			os.Remove(pidPath)
+		}
+		return fmt.Errorf("sathub-client is not running (removed stale PID file for PID %d)", pid)
+	}
+
+	if dryRun {
+		fmt.Printf("[dry-run] Would send SIGTERM to PID %d\n", pid)
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", pid, err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to send SIGTERM to PID %d: %w", pid, err)
+	}
+	if !jsonOutput {
+		fmt.Printf("Sent SIGTERM to PID %d, waiting for in-flight uploads to finish...\n", pid)
+	}
+
+	timeout := stopTimeout(cfg.Intervals.ShutdownTimeout)
+	start := time.Now()
+	lastReport := start
+	killed := false
+	for processAlive(pid) {
+		if time.Since(start) >= timeout {
+			if !jsonOutput {
+				fmt.Printf("Still running after %s, sending SIGKILL\n", timeout)
+			}
+			if err := process.Kill(); err != nil {
+				return fmt.Errorf("failed to kill PID %d: %w", pid, err)
+			}
+			killed = true
+			// The killed client cannot remove its PID file
+			os.Remove(pidPath)
+			break
+		}
+		if !jsonOutput && time.Since(lastReport) >= 5*time.Second {
+			fmt.Printf("Waiting for PID %d to exit (%s)...\n", pid, time.Since(start).Round(time.Second))
+			lastReport = time.Now()
+		}
+		time.Sleep(stopPollInterval)
+	}
+
+	if jsonOutput {
+		PrintJSON(map[string]interface{}{
+			"pid":    pid,
+			"killed": killed,
+		})
+		return nil
+	}
+	if killed {
+		fmt.Printf("✓ Killed sathub-client (PID %d)\n", pid)
+	} else {
+		fmt.Printf("✓ Stopped sathub-client (PID %d)\n", pid)
+	}
+	return nil
+}
+
+// runningClientPIDs returns the PIDs of other sathub-client processes found with pgrep
+func runningClientPIDs() []string {
+	output, err := exec.Command("pgrep", "-x", "sathub-client").Output()
+	if err != nil {
+		return nil
+	}
+	self := strconv.Itoa(os.Getpid())
+	var pids []string
+	for _, pid := range strings.Fields(string(output)) {
+		if pid != self {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopTimeout(t *testing.T) {
+	tests := []struct {
+		shutdownTimeoutSec int
+		want               time.Duration
+	}{
+		{0, 150 * time.Second}, // default shutdown timeout, matches TimeoutStopSec of the systemd unit
+		{-5, 150 * time.Second},
+		{1, 31 * time.Second},
+		{60, 90 * time.Second},
+		{600, 630 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := stopTimeout(tt.shutdownTimeoutSec); got != tt.want {
+			t.Errorf("stopTimeout(%d) = %s, want %s", tt.shutdownTimeoutSec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is a small persistent key-value store backed by a JSON file.
+// Every write rewrites the file atomically, so it is only suited for small data sets.
+type FileStore struct {
+	path string
+	mu   sync.RWMutex
+	data map[string]string
+}
+
+// NewFileStore opens the store at path, creating it if it doesn't exist
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{
+		path: path,
+		data: make(map[string]string),
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	}
+
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &store.data); err != nil {
+			return nil, fmt.Errorf("failed to parse store file %s: %w", path, err)
+		}
+	}
+
+	return store, nil
+}
+
+// Get returns the value stored for key
+func (s *FileStore) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// Set stores value for key and persists the store
+func (s *FileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return s.save()
+}
+
+// Delete removes key and persists the store
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; !ok {
+		return nil
+	}
+	delete(s.data, key)
+	return s.save()
+}
+
+// All returns a copy of all stored entries
+func (s *FileStore) All() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make(map[string]string, len(s.data))
+	for key, value := range s.data {
+		entries[key] = value
+	}
+	return entries
+}
+
+// save writes the store to disk via a temporary file, callers must hold the write lock
+func (s *FileStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal store: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+	return nil
+}
+
+// ChecksumStore maps dataset.json checksums to the post created for them,
+// so a pass that is processed twice reuses its existing post
+type ChecksumStore struct {
+	store *FileStore
+}
+
+// NewChecksumStore opens the checksum store at path
+func NewChecksumStore(path string) (*ChecksumStore, error) {
+	store, err := NewFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ChecksumStore{store: store}, nil
+}
+
+// Lookup returns the post ID recorded for checksum
+func (cs *ChecksumStore) Lookup(checksum string) (string, bool) {
+	return cs.store.Get(checksum)
+}
+
+// Save records the post ID created for checksum
+func (cs *ChecksumStore) Save(checksum, postID string) error {
+	return cs.store.Set(checksum, postID)
+}
+
+// DeletePost removes all checksums that point to postID
+func (cs *ChecksumStore) DeletePost(postID string) error {
+	for checksum, id := range cs.store.All() {
+		if id == postID {
+			if err := cs.store.Delete(checksum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PassRecord is one processed pass in the pass history
+type PassRecord struct {
+	ID          string    `json:"id"`
+	Path        string    `json:"path"`
+	Satellite   string    `json:"satellite"`
+	PostID      string    `json:"post_id"`
+	ProcessedAt time.Time `json:"processed_at"`
+	DurationMS  int64     `json:"duration_ms"` // Time spent processing the pass
+	ImageCount  int       `json:"image_count"`
+	Success     bool      `json:"success"`
+}
+
+// PassFilter selects passes from the pass history, zero values match everything
+type PassFilter struct {
+	Since      time.Time // Only passes processed at or after this time
+	Satellite  string    // Only passes of this satellite (case-insensitive)
+	FailedOnly bool      // Only passes that failed
+	Limit      int       // Maximum number of passes, newest first
+}
+
+// ProcessedStore keeps the history of processed passes
+type ProcessedStore interface {
+	RecordPass(record PassRecord) error
+	QueryPasses(filter PassFilter) ([]PassRecord, error)
+	CountPasses(success bool, pathPrefixes []string) (int, error)
+	Close() error
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sathub-client/config"
+)
+
+// buildTLSConfig returns the TLS configuration for connections to the API and WebSocket:
+// insecure, tls_ca_cert, tls_client_cert and tls_client_key from opts
+func buildTLSConfig(opts config.OptionsConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if err := applyTLSFiles(tlsConfig, opts.TLSCACert, opts.TLSClientCert, opts.TLSClientKey); err != nil {
+		return nil, err
+	}
+	return tlsConfig, nil
+}
+
+// isCertificateError reports whether err is caused by a server certificate that failed verification
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &hostnameErr)
+}
+
+// applyTLSFiles adds a custom CA certificate and a client certificate for mutual TLS to tlsConfig.
+// Empty paths are skipped; the client certificate is only loaded when both cert and key are set.
+func applyTLSFiles(tlsConfig *tls.Config, caCertPath, clientCertPath, clientKeyPath string) error {
+	if caCertPath != "" {
+		pem, err := os.ReadFile(config.ExpandPath(caCertPath))
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificates found in %s", caCertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if clientCertPath != "" && clientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(config.ExpandPath(clientCertPath), config.ExpandPath(clientKeyPath))
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if clientCertPath != "" || clientKeyPath != "" {
+		return fmt.Errorf("both tls_client_cert and tls_client_key must be set for mutual TLS")
+	}
+
+	return nil
+}
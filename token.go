@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sathub-client/config"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the station token",
+}
+
+var tokenValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Verify the station token against the API",
+	Long:  "Send a health check with the configured station token (or the one given with --token) and report whether the API accepts it.",
+	Example: `  # Validate the token from the config file
+  sathub-client token validate
+
+  # Validate a token before putting it in the config file
+  sathub-client token validate --token <token>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateToken()
+	},
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenValidateCmd)
+}
+
+// validateToken checks the station token with a health check and explains the result
+func validateToken() error {
+	tokenCfg, err := readConfig()
+	if err != nil {
+		if flagToken == "" {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		// A token was given explicitly, the config file is only needed for the API URL
+		fmt.Fprintf(os.Stderr, "Warning: %v, using default API URL\n", err)
+		tokenCfg = config.Default()
+		applyFlagOverrides(tokenCfg)
+	}
+
+	// --token is applied by readConfig
+	station := tokenCfg.PrimaryStation()
+	token := station.Token
+	if token == "" {
+		return fmt.Errorf("no station token configured, set station.token or pass --token")
+	}
+	if trimmed := strings.TrimSpace(token); trimmed != token {
+		fmt.Fprintln(os.Stderr, "Warning: token has leading or trailing whitespace, it will be rejected by the API")
+	}
+
+	apiClient, err := newAPIClient(tokenCfg, station)
+	if err != nil {
+		return err
+	}
+	healthResp, err := apiClient.StationHealth(context.Background())
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+			return fmt.Errorf("token %s was rejected by %s: make sure the complete Station API Token was copied from your station page", maskToken(token), station.APIURL)
+		}
+		if errors.As(err, &apiErr) {
+			return fmt.Errorf("API at %s could not validate the token: %w", station.APIURL, err)
+		}
+		return fmt.Errorf("could not reach API at %s, the token was not checked: %w", station.APIURL, err)
+	}
+
+	stationName := healthResp.StationName
+	if stationName == "" {
+		stationName = "unknown"
+	}
+
+	if jsonOutput {
+		PrintJSON(map[string]interface{}{
+			"valid":        true,
+			"station_id":   healthResp.StationID,
+			"station_name": healthResp.StationName,
+		})
+		return nil
+	}
+
+	fmt.Println("✓ Token accepted")
+	fmt.Printf("  Station ID:   %s\n", healthResp.StationID)
+	fmt.Printf("  Station Name: %s\n", stationName)
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+)
+
+// releaseBaseURL is where release archives and their signatures are published
+const releaseBaseURL = "https://github.com/vleeuwenmenno/sathub-client/releases/latest/download"
+
+// maxReleaseSize limits how much is downloaded for a release archive
+const maxReleaseSize = 100 * 1024 * 1024
+
+// updatePublicKey is the minisign public key release archives are signed with
+const updatePublicKey = "RWQMo6qyDEHm/z14WNIHjDCPqYMAfJRyGDF7Jf0CMTFugtUZOhcb6U4S"
+
+// SignatureError is returned when a downloaded release does not match its signature
+type SignatureError struct {
+	Asset string
+}
+
+// Error implements the error interface
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("signature verification failed for %s, the download may have been tampered with", e.Asset)
+}
+
+// updateClient downloads the latest release, verifies its signature and replaces the running binary
+func updateClient() error {
+	var publicKey minisign.PublicKey
+	if err := publicKey.UnmarshalText([]byte(updatePublicKey)); err != nil {
+		return fmt.Errorf("invalid update signing key: %w", err)
+	}
+
+	asset := fmt.Sprintf("sathub-client-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archiveURL := releaseBaseURL + "/" + asset
+
+	fmt.Printf("Downloading latest version from %s...\n", archiveURL)
+	archive, err := downloadRelease(archiveURL)
+	if err != nil {
+		return err
+	}
+	signature, err := downloadRelease(archiveURL + ".minisig")
+	if err != nil {
+		return err
+	}
+
+	if !verifyRelease(publicKey, archive, signature) {
+		return &SignatureError{Asset: asset}
+	}
+	fmt.Println("✓ Signature verified")
+
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return err
+	}
+
+	targetPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate current binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(targetPath); err == nil {
+		targetPath = resolved
+	}
+
+	if err := replaceBinary(targetPath, binary); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Updated %s\n", targetPath)
+	fmt.Println("Restart the service to run the new version")
+	return nil
+}
+
+// downloadRelease fetches a release file into memory
+func downloadRelease(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReleaseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if len(data) > maxReleaseSize {
+		return nil, fmt.Errorf("download %s exceeds %d bytes", url, maxReleaseSize)
+	}
+	return data, nil
+}
+
+// verifyRelease checks archive against signature, accepting both plain and pre-hashed
+// signatures since the minisign CLI signs pre-hashed by default
+func verifyRelease(publicKey minisign.PublicKey, archive, signature []byte) bool {
+	var sig minisign.Signature
+	if err := sig.UnmarshalText(signature); err != nil {
+		return false
+	}
+	if sig.Algorithm != minisign.HashEdDSA {
+		return minisign.Verify(publicKey, archive, signature)
+	}
+	reader := minisign.NewReader(bytes.NewReader(archive))
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return false
+	}
+	return reader.Verify(publicKey, signature)
+}
+
+// extractBinary returns the sathub-client executable from a release archive
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open release archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read release archive: %w", err)
+		}
+
+		name := filepath.Base(header.Name)
+		if header.Typeflag == tar.TypeReg && strings.TrimSuffix(name, ".exe") == "sathub-client" {
+			binary, err := io.ReadAll(io.LimitReader(tr, maxReleaseSize))
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract binary: %w", err)
+			}
+			return binary, nil
+		}
+	}
+
+	return nil, fmt.Errorf("release archive does not contain a sathub-client binary")
+}
+
+// replaceBinary atomically replaces the binary at targetPath with a rename in the same directory
+func replaceBinary(targetPath string, binary []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), ".sathub-client-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file (try running with sudo): %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.Write(binary); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// versionManifestURL returns the latest released version as {"latest": "1.2.3"}
+const versionManifestURL = "https://api.sathub.de/version"
+
+// Update check results
+const (
+	updateStatusUpToDate = "up_to_date"
+	updateStatusOutdated = "outdated"
+	updateStatusAhead    = "ahead"
+)
+
+// versionCheckCacheTTL is how long version --check reuses the latest version it fetched
+const versionCheckCacheTTL = 24 * time.Hour
+
+// errUpdateAvailable makes version --check exit with 1, the result is already printed
+var errUpdateAvailable = errors.New("update available")
+
+// versionCheckCache is the latest version remembered by version --check
+type versionCheckCache struct {
+	Latest    string    `json:"latest"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// UpdateCheckResult is the outcome of comparing the running version with the latest release
+type UpdateCheckResult struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	Status  string `json:"status"`
+}
+
+var updateCheckCmd = &cobra.Command{
+	Use:   "update-check",
+	Short: "Check whether a newer version is available",
+	Long:  "Compare the running version with the latest released version without installing anything. Run 'sathub-client update' to install it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := checkForUpdate()
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			PrintJSON(result)
+			return nil
+		}
+
+		switch result.Status {
+		case updateStatusOutdated:
+			fmt.Printf("A new version is available: %s (current: %s)\n", result.Latest, result.Current)
+			fmt.Println("Run 'sathub-client update' to install it")
+		case updateStatusAhead:
+			fmt.Printf("Current version %s is ahead of the latest release %s\n", result.Current, result.Latest)
+		default:
+			fmt.Printf("✓ sathub-client %s is up to date\n", result.Current)
+		}
+		return nil
+	},
+}
+
+// checkForUpdate fetches the version manifest and compares it with VERSION
+func checkForUpdate() (*UpdateCheckResult, error) {
+	latest, err := fetchLatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	return newUpdateCheckResult(latest), nil
+}
+
+// cachedCheckForUpdate is checkForUpdate reusing the latest version fetched within versionCheckCacheTTL
+func cachedCheckForUpdate() (*UpdateCheckResult, error) {
+	path := versionCheckCachePath()
+	var cache versionCheckCache
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cache) == nil &&
+		cache.Latest != "" && time.Since(cache.CheckedAt) < versionCheckCacheTTL {
+		return newUpdateCheckResult(cache.Latest), nil
+	}
+
+	latest, err := fetchLatestVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to cache only costs another request next time
+	cache = versionCheckCache{Latest: latest, CheckedAt: time.Now()}
+	if data, err := json.MarshalIndent(cache, "", "  "); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			os.WriteFile(path, data, 0644)
+		}
+	}
+	return newUpdateCheckResult(latest), nil
+}
+
+// versionCheckCachePath returns where version --check caches the latest version
+func versionCheckCachePath() string {
+	return filepath.Join(config.ExpandPath(config.DefaultDataDir), "version-check.json")
+}
+
+// newUpdateCheckResult compares VERSION with the latest released version
+func newUpdateCheckResult(latest string) *UpdateCheckResult {
+	result := &UpdateCheckResult{Current: VERSION, Latest: latest, Status: updateStatusUpToDate}
+	switch compareVersions(VERSION, latest) {
+	case -1:
+		result.Status = updateStatusOutdated
+	case 1:
+		result.Status = updateStatusAhead
+	}
+	return result
+}
+
+// fetchLatestVersion returns the latest released version from the version manifest
+func fetchLatestVersion() (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(versionManifestURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch version manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("version manifest request failed: %w", &APIError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	var manifest struct {
+		Latest string `json:"latest"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return "", fmt.Errorf("failed to decode version manifest: %w", err)
+	}
+	latest := strings.TrimPrefix(strings.TrimSpace(manifest.Latest), "v")
+	if latest == "" {
+		return "", fmt.Errorf("version manifest does not contain a latest version")
+	}
+	return latest, nil
+}
+
+// logUpdateCheck checks for a newer version and logs the result, intended to run in the background
+func logUpdateCheck() {
+	result, err := checkForUpdate()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Update check failed")
+		return
+	}
+
+	if result.Status == updateStatusOutdated {
+		logger.Info().
+			Str("current", result.Current).
+			Str("latest", result.Latest).
+			Msg("A new version is available, run 'sathub-client update' to install it")
+		return
+	}
+	logger.Debug().Str("current", result.Current).Str("latest", result.Latest).Str("status", result.Status).Msg("Update check completed")
+}
+
+// runVersionCheck prints how VERSION compares to the latest release and returns errUpdateAvailable when it is outdated
+func runVersionCheck() error {
+	result, err := cachedCheckForUpdate()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		PrintJSON(result)
+	} else {
+		switch result.Status {
+		case updateStatusOutdated:
+			fmt.Printf("Update available: %s (current: %s)\n", result.Latest, result.Current)
+		case updateStatusAhead:
+			fmt.Printf("Ahead of release: %s\n", result.Current)
+		default:
+			fmt.Printf("Up to date (%s)\n", result.Current)
+		}
+	}
+
+	if result.Status == updateStatusOutdated {
+		return errUpdateAvailable
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sathub-client/config"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// DirectoryReport summarizes what a pass directory contains and whether it would be processed
+type DirectoryReport struct {
+	Path            string   `json:"path"`
+	SatelliteName   string   `json:"satellite_name,omitempty"`
+	Timestamp       string   `json:"timestamp,omitempty"`
+	ProductDirs     int      `json:"product_dirs"`
+	HasCBOR         bool     `json:"has_cbor"`
+	ImageCount      int      `json:"image_count"`
+	HasCADU         bool     `json:"has_cadu"`
+	AllowedByFilter bool     `json:"allowed_by_filter"`
+	WouldProcess    bool     `json:"would_process"`
+	Missing         []string `json:"missing,omitempty"`
+}
+
+var validateDirectoryCmd = &cobra.Command{
+	Use:   "validate-directory <path>",
+	Short: "Check whether a directory is a complete satellite pass",
+	Long:  "Inspect a pass directory the same way the watcher does and report what was found and what is missing. Exits with 0 if the directory would be processed and 1 if it would be skipped.",
+	Args:  cobra.ExactArgs(1),
+	// Pass directories are completed, not files
+	ValidArgsFunction: completeDirectory,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateDirectory(args[0])
+	},
+}
+
+// validateDirectory reports on a pass directory and returns an error if it would be skipped
+func validateDirectory(dirPath string) error {
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to access directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dirPath)
+	}
+
+	// The config file is only needed for satellite aliases and filters, don't create one
+	cfg = config.Default()
+	if configFromEnv {
+		config.ApplyEnvironmentOverrides(cfg)
+	} else if _, err := os.Stat(config.GetConfigPath(configPath)); err == nil {
+		if loaded, err := config.Load(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, using default filters\n", err)
+		} else {
+			cfg = loaded
+		}
+	}
+	fw := &FileWatcher{config: newWatcherConfig(), logger: logger}
+
+	report := inspectDirectory(fw, dirPath)
+
+	if jsonOutput {
+		PrintJSON(report)
+	} else {
+		printDirectoryReport(report)
+	}
+
+	if !report.WouldProcess {
+		return fmt.Errorf("%s would be skipped", dirPath)
+	}
+	return nil
+}
+
+// inspectDirectory collects the files the watcher looks at when processing a pass
+func inspectDirectory(fw *FileWatcher, dirPath string) DirectoryReport {
+	report := DirectoryReport{Path: dirPath}
+
+	datasetPath := filepath.Join(dirPath, "dataset.json")
+	if _, err := os.Stat(datasetPath); err != nil {
+		report.Missing = append(report.Missing, "dataset.json")
+	} else if dataset, err := fw.parseJSONFile(datasetPath); err != nil {
+		report.Missing = append(report.Missing, fmt.Sprintf("valid dataset.json (%v)", err))
+	} else {
+		report.SatelliteName = NormalizeSatelliteName(dataset.SatelliteName, fw.config.GetSatelliteAliases())
+		if hasValidTimestamp(datasetPath) {
+			report.Timestamp = dataset.Timestamp.Format(time.RFC3339)
+		} else {
+			report.Missing = append(report.Missing, "RFC 3339 timestamp in dataset.json (current time would be used)")
+		}
+	}
+
+	if matches, err := filepath.Glob(filepath.Join(dirPath, "*.cadu")); err == nil && len(matches) > 0 {
+		report.HasCADU = true
+	}
+
+	if entries, err := os.ReadDir(dirPath); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			productDir := filepath.Join(dirPath, entry.Name())
+			if _, err := os.Stat(filepath.Join(productDir, "product.cbor")); err != nil {
+				continue
+			}
+			report.ProductDirs++
+			report.HasCBOR = true
+
+			productEntries, err := os.ReadDir(productDir)
+			if err != nil {
+				continue
+			}
+			for _, productEntry := range productEntries {
+				if isImageFile(productEntry.Name()) {
+					report.ImageCount++
+				}
+			}
+		}
+	}
+
+	if sentinel := fw.config.CompletionSentinel; sentinel != "" {
+		if _, err := os.Stat(filepath.Join(dirPath, sentinel)); err != nil {
+			report.Missing = append(report.Missing, fmt.Sprintf("completion sentinel %s", sentinel))
+		}
+	} else if !report.HasCBOR && !report.HasCADU {
+		report.Missing = append(report.Missing, "product directory with product.cbor, or a .cadu file")
+	}
+
+	report.AllowedByFilter = fw.isSatelliteAllowed(dirPath)
+	report.WouldProcess = fw.isCompleteSatellitePass(dirPath) && report.AllowedByFilter
+	return report
+}
+
+// hasValidTimestamp reports whether dataset.json has a timestamp parseJSONFile accepts
+func hasValidTimestamp(datasetPath string) bool {
+	data, err := os.ReadFile(datasetPath)
+	if err != nil {
+		return false
+	}
+
+	var raw struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return false
+	}
+	_, err = time.Parse(time.RFC3339, raw.Timestamp)
+	return err == nil
+}
+
+// printDirectoryReport prints a directory report for humans
+func printDirectoryReport(report DirectoryReport) {
+	yesNo := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Printf("Directory:    %s\n", report.Path)
+	fmt.Printf("Satellite:    %s\n", report.SatelliteName)
+	fmt.Printf("Timestamp:    %s\n", report.Timestamp)
+	fmt.Printf("Product dirs: %d\n", report.ProductDirs)
+	fmt.Printf("CBOR:         %s\n", yesNo(report.HasCBOR))
+	fmt.Printf("Images:       %d\n", report.ImageCount)
+	fmt.Printf("CADU:         %s\n", yesNo(report.HasCADU))
+	if report.AllowedByFilter {
+		fmt.Println("Filters:      allowed")
+	} else {
+		fmt.Println("Filters:      excluded by include/exclude filters")
+	}
+
+	if len(report.Missing) > 0 {
+		fmt.Println()
+		fmt.Println("Missing:")
+		for _, missing := range report.Missing {
+			fmt.Printf("  - %s\n", missing)
+		}
+	}
+
+	fmt.Println()
+	if report.WouldProcess {
+		fmt.Println("✓ This directory would be processed")
+	} else {
+		fmt.Println("✗ This directory would be skipped")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		v1, v2 string
+		want   int
+	}{
+		{"1.0.0", "0.9.9", 1},
+		{"0.9.9", "1.0.0", -1},
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.2.0", "1.10.0", -1},
+		{"1.2.3", "1.2.3-beta", 1},
+		{"1.2.3-beta", "1.2.3", -1},
+		{"1.2.3-beta.2", "1.2.3-beta.11", -1},
+		{"1.2.3-alpha", "1.2.3-beta", -1},
+		{"1.2.3-beta", "1.2.3-beta.1", -1},
+		{"1.2.3-1", "1.2.3-alpha", -1},
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.4", "1.2.3", 1},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"v1.2.3-rc.1+build.7", "1.2.3-rc.1", 0},
+		{"1.2", "1.2.0", 0},
+		{"dev", "0.0.0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.v1+"_vs_"+tt.v2, func(t *testing.T) {
+			if got := compareVersions(tt.v1, tt.v2); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version             string
+		major, minor, patch int
+		prerelease          string
+		wantErr             bool
+	}{
+		{version: "1.2.3", major: 1, minor: 2, patch: 3},
+		{version: "v1.2.3", major: 1, minor: 2, patch: 3},
+		{version: " v1.2.3 ", major: 1, minor: 2, patch: 3},
+		{version: "1.2.3-beta.1", major: 1, minor: 2, patch: 3, prerelease: "beta.1"},
+		{version: "v1.2.3-beta.1+build.5", major: 1, minor: 2, patch: 3, prerelease: "beta.1"},
+		{version: "1.2.3+build", major: 1, minor: 2, patch: 3},
+		{version: "2", major: 2},
+		{version: "2.1", major: 2, minor: 1},
+		{version: "1.2.3-", wantErr: true},
+		{version: "1.2.3.4", wantErr: true},
+		{version: "1.x.3", wantErr: true},
+		{version: "1.-2.3", wantErr: true},
+		{version: "", wantErr: true},
+		{version: "dev", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			major, minor, patch, prerelease, err := ParseSemver(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSemver(%q) succeeded, want error", tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSemver(%q) failed: %v", tt.version, err)
+			}
+			if major != tt.major || minor != tt.minor || patch != tt.patch || prerelease != tt.prerelease {
+				t.Errorf("ParseSemver(%q) = %d, %d, %d, %q, want %d, %d, %d, %q",
+					tt.version, major, minor, patch, prerelease, tt.major, tt.minor, tt.patch, tt.prerelease)
+			}
+		})
+	}
+}
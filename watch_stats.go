@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+var watchStatsWindow time.Duration
+
+var watchStatsCmd = &cobra.Command{
+	Use:   "watch-stats",
+	Short: "Count file system events in the watch directory",
+	Long: `Watch the watch directory and the pass directories below it for a sampling window and
+print how many create, write, remove, rename and chmod events each directory received.
+Nothing is uploaded. Use it to find noisy directories, e.g. before tuning process_delay.`,
+	Example: `  # Sample for five minutes
+  sathub-client watch-stats --window 5m`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		watcherConfig := newWatcherConfig()
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("failed to create watcher: %w", err)
+		}
+		defer watcher.Close()
+
+		// Pass directories are one level below the deepest watched level
+		watched := 0
+		for _, watchPath := range watcherConfig.WatchPaths {
+			n, err := addWatchTree(watcher, watchPath, watcherConfig.RecursiveDepth+1)
+			if err != nil {
+				return err
+			}
+			watched += n
+		}
+
+		counter := NewEventCounter()
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		timer := time.NewTimer(watchStatsWindow)
+		defer timer.Stop()
+
+		if !jsonOutput {
+			fmt.Printf("Watching %d directories for %s (Ctrl+C to stop early)...\n", watched, watchStatsWindow)
+		}
+		start := time.Now()
+
+	sample:
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					break sample
+				}
+				counter.Record(event)
+				// Follow new pass directories so their file events are counted too
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watcher.Add(event.Name)
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					break sample
+				}
+				logger.Warn().Err(err).Msg("Watcher error")
+			case <-timer.C:
+				break sample
+			case <-sigChan:
+				break sample
+			}
+		}
+
+		stats := counter.Snapshot()
+		if jsonOutput {
+			PrintJSON(map[string]interface{}{
+				"window_seconds": int64(time.Since(start).Seconds()),
+				"directories":    stats,
+			})
+			return nil
+		}
+
+		if len(stats) == 0 {
+			fmt.Printf("No events in %s.\n", time.Since(start).Round(time.Second))
+			return nil
+		}
+		fmt.Printf("\n%8s  %8s  %8s  %8s  %8s  %8s  %s\n", "CREATE", "WRITE", "REMOVE", "RENAME", "CHMOD", "TOTAL", "DIRECTORY")
+		for _, entry := range stats {
+			fmt.Printf("%8d  %8d  %8d  %8d  %8d  %8d  %s\n",
+				entry.Create, entry.Write, entry.Remove, entry.Rename, entry.Chmod, entry.Total, entry.Dir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	watchStatsCmd.Flags().DurationVar(&watchStatsWindow, "window", 60*time.Second, "How long to sample events")
+}
+
+// addWatchTree adds root and its subdirectories up to maxDepth levels below it to watcher,
+// returning the number of watched directories
+func addWatchTree(watcher *fsnotify.Watcher, root string, maxDepth int) (int, error) {
+	if err := watcher.Add(root); err != nil {
+		return 0, fmt.Errorf("failed to watch %s: %w", root, err)
+	}
+	watched := 1
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == root {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			logger.Warn().Err(err).Str("path", path).Msg("Failed to watch directory")
+		} else {
+			watched++
+		}
+		if dirDepth(root, path) >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return watched, nil
+}
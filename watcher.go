@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"sathub-client/metrics"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -25,42 +37,155 @@ type SatelliteData struct {
 type FileWatcher struct {
 	config    *Config
 	apiClient *APIClient
-	watcher   *fsnotify.Watcher
+	watcher   dirWatcher
 	processed map[string]bool // Track processed directories
+	mu        sync.Mutex      // Protects processed, incomplete, the last pass times and config.WatchPaths, scans may run concurrently
+	checksums *ChecksumStore  // Maps dataset.json checksums to created posts
+	history   ProcessedStore  // History of processed passes, nil if it couldn't be opened
+	retries   *RetryQueue     // Passes that failed and are resumed on the next attempt
+	metrics   *metrics.Collector
+	events    *EventCounter  // Optional file system event statistics
+	paused    int32          // Set atomically, 1 while processing is paused
+	inFlight  sync.WaitGroup // Passes currently being uploaded, drained by Stop
+	stopping  bool           // Set under mu by Stop, no new passes are started afterwards
 	logger    zerolog.Logger
+
+	// incomplete holds pass directories that weren't complete when they were seen, they are checked again periodically
+	incomplete map[string]bool
+	locked     map[string]bool // Pass directories waiting for their SatDump lock file to disappear
+	stopCh     chan struct{}   // Closed by Stop to end the periodic checks
+
+	// Pass counters reported by Stats, the times and error are protected by mu
+	stats           passStats
+	lastProcessedAt *time.Time
+	lastFailedAt    *time.Time
+	lastFailedError string
+
+	onDiskSpaceLow func(freeMB int64)
+	forceNewPosts  bool // Set by reprocess and upload, the checksums of earlier uploads are ignored
 }
 
 // NewFileWatcher creates a new file watcher
 func NewFileWatcher(config *Config, apiClient *APIClient) (*FileWatcher, error) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	fw := &FileWatcher{
+		config:     config,
+		apiClient:  apiClient,
+		processed:  make(map[string]bool),
+		incomplete: make(map[string]bool),
+		locked:     make(map[string]bool),
+		stopCh:     make(chan struct{}),
+		stats:      passStats{startTime: time.Now()},
+		logger:     logBase.With().Str("component", "watcher").Logger(),
+	}
+	if config.StationID != "" {
+		fw.logger = fw.logger.With().Str("station_id", config.StationID).Logger()
 	}
 
-	fw := &FileWatcher{
-		config:    config,
-		apiClient: apiClient,
-		watcher:   watcher,
-		processed: make(map[string]bool),
-		logger:    logger.With().Str("component", "watcher").Logger(),
+	watcher, err := fw.newDirWatcher()
+	if err != nil {
+		return nil, err
 	}
+	fw.watcher = watcher
 
 	// Ensure processed directory exists
 	if err := os.MkdirAll(config.ProcessedDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create processed directory: %w", err)
 	}
 
+	// Open checksum store used to avoid creating duplicate posts
+	if config.DataDir != "" {
+		checksums, err := NewChecksumStore(filepath.Join(config.DataDir, "checksums.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open checksum store: %w", err)
+		}
+		fw.checksums = checksums
+
+		// Resume passes that failed before a restart instead of starting over
+		retries, err := NewRetryQueue(filepath.Join(config.DataDir, "retry-queue.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open retry queue: %w", err)
+		}
+		fw.retries = retries
+		fw.pruneRetryQueue()
+	}
+
+	// The pass history is informational, uploads continue without it
+	if config.HistoryDB != "" {
+		history, err := NewSQLiteStore(config.HistoryDB)
+		if err != nil {
+			fw.logger.Warn().Err(err).Msg("Failed to open pass history, passes will not be recorded")
+		} else {
+			fw.history = history
+		}
+	}
+
 	return fw, nil
 }
 
+// newDirWatcher uses inotify unless polling is configured, a watch path is on a network filesystem
+// or inotify can't be set up because the process ran out of file descriptors
+func (fw *FileWatcher) newDirWatcher() (dirWatcher, error) {
+	if fw.config.UsePolling {
+		fw.logger.Info().Dur("interval", fw.config.PollingInterval).Msg("Polling watch directories for changes")
+		return newPollingWatcher(fw.config.PollingInterval), nil
+	}
+
+	for _, path := range fw.config.WatchPaths {
+		if fsType := networkFilesystem(path); fsType != "" {
+			fw.logger.Warn().
+				Str("path", path).
+				Str("filesystem", fsType).
+				Dur("interval", fw.config.PollingInterval).
+				Msg("Watch path is on a network filesystem, polling for changes instead of using inotify")
+			return newPollingWatcher(fw.config.PollingInterval), nil
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		if errors.Is(err, syscall.EMFILE) || strings.Contains(err.Error(), "too many open files") {
+			fw.logger.Warn().Err(err).Dur("interval", fw.config.PollingInterval).Msg("Failed to create inotify watcher, polling for changes instead")
+			return newPollingWatcher(fw.config.PollingInterval), nil
+		}
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	return &notifyWatcher{watcher: watcher}, nil
+}
+
+// SetMetrics sets the optional collector used to record processing metrics
+func (fw *FileWatcher) SetMetrics(collector *metrics.Collector) {
+	fw.metrics = collector
+}
+
+// SetEventCounter sets the optional counter that records every file system event received
+func (fw *FileWatcher) SetEventCounter(counter *EventCounter) {
+	fw.events = counter
+}
+
+// SetForceNewPosts makes passes create a new post even when their dataset was uploaded before,
+// for uploading a pass again on request
+func (fw *FileWatcher) SetForceNewPosts(force bool) {
+	fw.forceNewPosts = force
+}
+
+// SetOnDiskSpaceLow sets the callback invoked when an upload is skipped for lack of disk space
+func (fw *FileWatcher) SetOnDiskSpaceLow(callback func(freeMB int64)) {
+	fw.onDiskSpaceLow = callback
+}
+
 // Start begins watching the configured directories
 func (fw *FileWatcher) Start() error {
+	if fw.config.RecursiveDepth > 2 {
+		fw.logger.Warn().Int("recursive_depth", fw.config.RecursiveDepth).Msg("Deep recursive watching uses many inotify watches")
+	}
+
 	// Watch all configured paths
 	for _, path := range fw.config.WatchPaths {
 		if err := fw.watcher.Add(path); err != nil {
 			fw.logger.Warn().Err(err).Str("path", path).Msg("Failed to watch path")
 			continue
 		}
+		fw.watchIntermediateDirs(path, 0)
 		fw.logger.Info().Str("path", path).Msg("Watching directory")
 	}
 
@@ -69,13 +194,82 @@ func (fw *FileWatcher) Start() error {
 
 	// Start the watch loop
 	go fw.watchLoop()
+	go fw.completenessLoop()
+
+	return nil
+}
+
+// AddWatchPath starts watching an additional directory and processes its existing passes
+func (fw *FileWatcher) AddWatchPath(path string) error {
+	fw.mu.Lock()
+	for _, existing := range fw.config.WatchPaths {
+		if existing == path {
+			fw.mu.Unlock()
+			return nil
+		}
+	}
+	fw.mu.Unlock()
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create watch directory: %w", err)
+	}
+	if err := fw.watcher.Add(path); err != nil {
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+	fw.watchIntermediateDirs(path, 0)
+
+	fw.mu.Lock()
+	fw.config.WatchPaths = append(fw.config.WatchPaths, path)
+	fw.mu.Unlock()
 
+	fw.logger.Info().Str("path", path).Msg("Watching directory")
+
+	go fw.processExistingDirectories()
 	return nil
 }
 
-// Stop stops the file watcher
+// Stop stops watching for new passes and waits up to the shutdown timeout
+// for passes that are being uploaded to finish
 func (fw *FileWatcher) Stop() error {
-	return fw.watcher.Close()
+	fw.mu.Lock()
+	if fw.stopping {
+		fw.mu.Unlock()
+		return nil
+	}
+	fw.stopping = true
+	fw.mu.Unlock()
+	close(fw.stopCh)
+
+	err := fw.watcher.Close()
+
+	done := make(chan struct{})
+	go func() {
+		fw.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(fw.config.ShutdownTimeout):
+		fw.logger.Warn().Dur("timeout", fw.config.ShutdownTimeout).Msg("Timed out waiting for in-flight uploads, exiting anyway")
+	}
+
+	if fw.history != nil {
+		fw.history.Close()
+	}
+
+	return err
+}
+
+// beginPass registers a pass as in flight, it returns false once Stop has been called
+func (fw *FileWatcher) beginPass() bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.stopping {
+		return false
+	}
+	fw.inFlight.Add(1)
+	return true
 }
 
 // watchLoop handles file system events
@@ -83,104 +277,390 @@ func (fw *FileWatcher) watchLoop() {
 	// Process existing directories first
 	fw.processExistingDirectories()
 
+	// A panic while handling one event must not stop the watcher
+	for !fw.watchEvents() {
+		fw.logger.Warn().Msg("Restarting watch loop")
+	}
+}
+
+// watchEvents handles file system events until the watcher is closed. It returns false
+// when it was stopped by a panic instead.
+func (fw *FileWatcher) watchEvents() (closed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(fw.logger, "watchLoop", r)
+		}
+	}()
+
 	for {
 		select {
-		case event, ok := <-fw.watcher.Events:
+		case event, ok := <-fw.watcher.Events():
 			if !ok {
-				return
+				return true
+			}
+			if fw.events != nil {
+				fw.events.Record(event)
+			}
+
+			// A pass moved away can come back under the same name, e.g. by dead-letter retry,
+			// and must be processed again then
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				fw.unmarkProcessed(event.Name)
 			}
 
 			if event.Has(fsnotify.Create) {
 				// Check if it's a directory (satellite pass)
 				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Nested directories may contain passes created later, watch them too
+					if depth := fw.watchDepth(event.Name); depth > 0 && depth < fw.config.RecursiveDepth {
+						for _, passDir := range fw.watchNewDir(event.Name, depth) {
+							fw.handleDirectoryEvent(passDir)
+						}
+					}
 					fw.handleDirectoryEvent(event.Name)
 				}
 			}
 
-		case err, ok := <-fw.watcher.Errors:
+		case err, ok := <-fw.watcher.Errors():
 			if !ok {
-				return
+				return true
 			}
 			fw.logger.Error().Err(err).Msg("Watcher error")
 		}
 	}
 }
 
+// Pause halts processing of new passes until Resume is called
+func (fw *FileWatcher) Pause() {
+	if atomic.CompareAndSwapInt32(&fw.paused, 0, 1) {
+		fw.logger.Info().Msg("Processing paused")
+	}
+}
+
+// Resume continues processing after a Pause
+func (fw *FileWatcher) Resume() {
+	if atomic.CompareAndSwapInt32(&fw.paused, 1, 0) {
+		fw.logger.Info().Msg("Processing resumed")
+	}
+}
+
+// IsPaused returns whether processing is currently paused
+func (fw *FileWatcher) IsPaused() bool {
+	return atomic.LoadInt32(&fw.paused) == 1
+}
+
+// waitWhilePaused blocks until processing is resumed, it returns false when the watcher is
+// stopped in the meantime and the pass must not be started
+func (fw *FileWatcher) waitWhilePaused(dirPath string) bool {
+	if !fw.IsPaused() {
+		return true
+	}
+
+	fw.logger.Info().Str("dir", dirPath).Msg("Processing is paused, waiting for resume")
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for fw.IsPaused() {
+		select {
+		case <-fw.stopCh:
+			fw.logger.Debug().Str("dir", dirPath).Msg("Stopped while paused, not processing satellite pass")
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
 // handleDirectoryEvent processes a new directory (satellite pass)
-func (fw *FileWatcher) handleDirectoryEvent(dirPath string) {
+func (fw *FileWatcher) handleDirectoryEvent(dirPath string) error {
+	// Hold off while paused for maintenance
+	if !fw.waitWhilePaused(dirPath) {
+		return nil
+	}
+
 	// Check if already processed
-	if fw.processed[dirPath] {
-		return
+	if fw.isProcessed(dirPath) {
+		return nil
+	}
+
+	// Passes that keep failing are set aside instead of being retried forever
+	if fw.retriesExhausted(dirPath) {
+		if fw.markProcessed(dirPath) {
+			fw.moveToDeadLetter(dirPath)
+		}
+		return nil
 	}
 
 	fw.logger.Info().Str("dir", dirPath).Msg("Detected new satellite pass directory")
 
+	// SatDump holds a lock file while it is writing, wait for it to go away instead of the fixed delay
+	if lockFile := findLockFile(dirPath); lockFile != "" {
+		fw.watchLock(dirPath, lockFile)
+		return nil
+	}
+
 	// Wait for the configured delay to allow sathub to complete processing
+	processDelay := fw.config.GetProcessDelay()
 	fw.logger.Info().
-		Dur("delay_ms", fw.config.ProcessDelay).
-		Int64("delay_seconds", int64(fw.config.ProcessDelay.Seconds())).
-		Int64("delay_minutes", int64(fw.config.ProcessDelay.Minutes())).
+		Dur("delay_ms", processDelay).
+		Int64("delay_seconds", int64(processDelay.Seconds())).
+		Int64("delay_minutes", int64(processDelay.Minutes())).
 		Msg("Waiting before processing")
-	time.Sleep(fw.config.ProcessDelay)
+	time.Sleep(processDelay)
 
 	// Check if this looks like a complete satellite pass
 	if !fw.isCompleteSatellitePass(dirPath) {
-		fw.logger.Warn().Str("dir", dirPath).Msg("Directory doesn't appear to be a complete satellite pass, skipping")
-		return
+		if fw.isIntermediateDir(dirPath) {
+			fw.logger.Debug().Str("dir", dirPath).Msg("Directory has no dataset.json, watching it for nested passes")
+		} else {
+			fw.logger.Warn().Str("dir", dirPath).Msg("Directory doesn't appear to be a complete satellite pass yet, checking it again later")
+			fw.trackIncomplete(dirPath)
+		}
+		return nil
 	}
 
-	// Mark as processed immediately
-	fw.processed[dirPath] = true
+	return fw.startPass(dirPath)
+}
+
+// startPass processes the pass in dirPath and moves it to the processed directory when it succeeded
+func (fw *FileWatcher) startPass(dirPath string) error {
+	// Don't start uploading once shutdown has begun
+	if !fw.beginPass() {
+		return nil
+	}
+	defer fw.inFlight.Done()
+
+	// Mark as processed immediately, another scan may have claimed it while we waited
+	if !fw.markProcessed(dirPath) {
+		return nil
+	}
+
+	// Skip satellites filtered out by the include/exclude lists (stays marked so it isn't re-checked)
+	if !fw.isSatelliteAllowed(dirPath) {
+		fw.recordSkipped()
+		return nil
+	}
 
 	// Process the directory
-	if err := fw.processSatellitePass(dirPath); err != nil {
+	if err := fw.processPass(dirPath); err != nil {
 		fw.logger.Error().Err(err).Str("dir", dirPath).Msg("Failed to process satellite pass")
-		// Remove from processed map on failure so it can be retried
-		delete(fw.processed, dirPath)
-		return
+		fw.metrics.PassFailed()
+		fw.recordFailed(err)
+		switch {
+		case fw.retriesExhausted(dirPath):
+			// Stays marked as processed, the directory is gone unless the move failed
+			fw.moveToDeadLetter(dirPath)
+		case !IsRetryable(err):
+			fw.skipPass(dirPath, err)
+		default:
+			// Remove from processed map on failure so it can be retried
+			fw.unmarkProcessed(dirPath)
+		}
+		return fmt.Errorf("%s: %w", dirPath, err)
 	}
 
 	// Move directory to processed
-	fw.moveDirectoryToProcessed(dirPath)
+	fw.recordProcessed()
+	if _, err := fw.moveDirectoryToProcessed(dirPath); err != nil {
+		fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to move directory to processed")
+	}
+	return nil
+}
+
+// findSatellitePassDirs returns the directories containing a dataset.json up to maxDepth levels below root.
+// Pass directories are not searched further. An error is only returned when root can't be read.
+func findSatellitePassDirs(root string, maxDepth int) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			logger.Warn().Err(err).Str("path", path).Msg("Failed to read directory")
+			return nil
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "dataset.json")); err == nil {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		if dirDepth(root, path) >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// watchNewDir watches a directory created depth levels below its watch path and the directories below it that
+// may hold passes. It returns the passes already in it: a pass created together with its parent, e.g. by
+// mkdir -p or a move, was written before the watch existed and raises no event of its own.
+func (fw *FileWatcher) watchNewDir(dir string, depth int) []string {
+	if err := fw.watcher.Add(dir); err != nil {
+		fw.logger.Warn().Err(err).Str("path", dir).Msg("Failed to watch directory")
+	}
+	fw.watchIntermediateDirs(dir, depth)
+
+	passDirs, err := findSatellitePassDirs(dir, fw.config.RecursiveDepth-depth)
+	if err != nil {
+		fw.logger.Warn().Err(err).Str("path", dir).Msg("Failed to read directory")
+	}
+	if len(passDirs) > 0 {
+		fw.logger.Debug().Str("dir", dir).Int("passes", len(passDirs)).Msg("New directory already contains satellite passes")
+	}
+	return passDirs
+}
+
+// watchIntermediateDirs adds fsnotify watches for the directories below dir that may
+// contain nested passes, dir itself is depth levels below its watch path
+func (fw *FileWatcher) watchIntermediateDirs(dir string, depth int) {
+	if depth+1 >= fw.config.RecursiveDepth {
+		return
+	}
+
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, "dataset.json")); err == nil {
+			return filepath.SkipDir
+		}
+		if err := fw.watcher.Add(path); err != nil {
+			fw.logger.Warn().Err(err).Str("path", path).Msg("Failed to watch directory")
+		}
+		if depth+dirDepth(dir, path)+1 >= fw.config.RecursiveDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
 }
 
-// processExistingDirectories processes satellite pass directories that already exist
-func (fw *FileWatcher) processExistingDirectories() {
+// watchDepth returns how many levels dirPath is below the watch path containing it, 0 if none does
+func (fw *FileWatcher) watchDepth(dirPath string) int {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
 	for _, watchPath := range fw.config.WatchPaths {
-		entries, err := os.ReadDir(watchPath)
+		if isInsideDir(dirPath, watchPath) {
+			return dirDepth(watchPath, dirPath)
+		}
+	}
+	return 0
+}
+
+// isIntermediateDir reports whether dirPath is a nested directory that may hold passes rather than a pass itself
+func (fw *FileWatcher) isIntermediateDir(dirPath string) bool {
+	if _, err := os.Stat(filepath.Join(dirPath, "dataset.json")); err == nil {
+		return false
+	}
+	return fw.watchDepth(dirPath) < fw.config.RecursiveDepth
+}
+
+// dirDepth returns how many path elements path is below root
+func dirDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// isProcessed reports whether a directory has already been processed
+func (fw *FileWatcher) isProcessed(dirPath string) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.processed[dirPath]
+}
+
+// markProcessed marks a directory as processed, returning false if it already was
+func (fw *FileWatcher) markProcessed(dirPath string) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.processed[dirPath] {
+		return false
+	}
+	fw.processed[dirPath] = true
+	return true
+}
+
+// unmarkProcessed removes a directory from the processed set so it can be retried
+func (fw *FileWatcher) unmarkProcessed(dirPath string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	delete(fw.processed, dirPath)
+}
+
+// processExistingDirectories processes satellite pass directories that already exist like new ones
+// and returns the errors of any passes that failed to process
+func (fw *FileWatcher) processExistingDirectories() []error {
+	return fw.scanExistingDirectories(false)
+}
+
+// scanExistingDirectories processes the satellite pass directories that already exist and returns the errors
+// of the passes that failed and of the watch paths that couldn't be read. With immediate set complete passes
+// are started right away instead of after the process delay, for a one-shot scan.
+func (fw *FileWatcher) scanExistingDirectories(immediate bool) []error {
+	var errs []error
+
+	fw.mu.Lock()
+	watchPaths := append([]string(nil), fw.config.WatchPaths...)
+	fw.mu.Unlock()
+
+	for _, watchPath := range watchPaths {
+		passDirs, err := findSatellitePassDirs(watchPath, fw.config.RecursiveDepth)
 		if err != nil {
-			fw.logger.Warn().Err(err).Str("path", watchPath).Msg("Failed to read directory")
-			continue
+			fw.logger.Warn().Err(err).Str("path", watchPath).Msg("Failed to read watch directory")
+			errs = append(errs, fmt.Errorf("failed to read watch directory: %w", err))
 		}
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
+		for _, dirPath := range passDirs {
+			if fw.isProcessed(dirPath) {
 				continue
 			}
 
-			dirPath := filepath.Join(watchPath, entry.Name())
-			if fw.processed[dirPath] {
+			if !fw.isCompleteSatellitePass(dirPath) && findLockFile(dirPath) == "" {
+				fw.trackIncomplete(dirPath)
+				continue
+			}
+			if !fw.isSatelliteAllowed(dirPath) {
 				continue
 			}
 
-			if fw.isCompleteSatellitePass(dirPath) {
-				fw.handleDirectoryEvent(dirPath)
+			var err error
+			switch {
+			case !immediate:
+				err = fw.handleDirectoryEvent(dirPath)
+			case findLockFile(dirPath) != "":
+				fw.logger.Info().Str("dir", dirPath).Msg("Satellite pass is still being written, skipping it")
+			case fw.retriesExhausted(dirPath):
+				if fw.markProcessed(dirPath) {
+					fw.moveToDeadLetter(dirPath)
+				}
+			default:
+				err = fw.startPass(dirPath)
+			}
+			if err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
+
+	return errs
 }
 
 // parseJSONFile parses JSON format satellite data with enhanced dataset.json support
 func (fw *FileWatcher) parseJSONFile(filePath string) (*SatelliteData, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to open %s: %w", filepath.Base(filePath), err)
 	}
 	defer file.Close()
 
 	var rawData map[string]interface{}
 	if err := json.NewDecoder(file).Decode(&rawData); err != nil {
-		return nil, err
+		return nil, &ParseError{File: filePath, Cause: err}
 	}
 
 	data := &SatelliteData{
@@ -258,44 +738,155 @@ func (fw *FileWatcher) parseJSONFile(filePath string) (*SatelliteData, error) {
 	return data, nil
 }
 
-// parseCBORTimestamps parses CBOR file and extracts the earliest valid timestamp
-func (fw *FileWatcher) parseCBORTimestamps(cborPath string) (time.Time, error) {
+// TimestampRange holds the earliest and latest valid timestamps of a pass
+type TimestampRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// extend widens the range to include other, zero times are ignored
+func (r *TimestampRange) extend(other TimestampRange) {
+	if !other.Start.IsZero() && (r.Start.IsZero() || other.Start.Before(r.Start)) {
+		r.Start = other.Start
+	}
+	if !other.End.IsZero() && (r.End.IsZero() || other.End.After(r.End)) {
+		r.End = other.End
+	}
+}
+
+// CBORResult holds the data extracted from a SatDump product.cbor file
+type CBORResult struct {
+	Timestamps TimestampRange // Zero if no valid timestamps were found
+	TLE1       string
+	TLE2       string
+	Instrument string
+}
+
+// parseCBOR parses a CBOR product file and extracts timestamps, TLE lines and the instrument name
+func (fw *FileWatcher) parseCBOR(cborPath string) (*CBORResult, error) {
 	file, err := os.Open(cborPath)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to open CBOR file: %w", err)
+		return nil, fmt.Errorf("failed to open CBOR file: %w", err)
 	}
 	defer file.Close()
 
 	var product SatDumpProduct
 	if err := cbor.NewDecoder(file).Decode(&product); err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse CBOR data: %w", err)
+		return nil, &ParseError{File: cborPath, Cause: err}
+	}
+
+	result := &CBORResult{
+		Instrument: product.Instrument,
+	}
+
+	if line1, ok := product.TLE["line1"].(string); ok {
+		result.TLE1 = line1
+	}
+	if line2, ok := product.TLE["line2"].(string); ok {
+		result.TLE2 = line2
 	}
 
-	if len(product.Timestamps) == 0 {
-		return time.Time{}, fmt.Errorf("no timestamps found in CBOR")
+	if timestamps, err := fw.parseCBORTimestamps(product.Timestamps); err != nil {
+		fw.logger.Warn().Err(err).Str("cbor", cborPath).Msg("Failed to extract timestamps from CBOR")
+	} else {
+		result.Timestamps = timestamps
+	}
+
+	fw.logger.Debug().
+		Str("instrument", result.Instrument).
+		Bool("has_tle", result.TLE1 != "" && result.TLE2 != "").
+		Msg("Parsed CBOR product")
+	return result, nil
+}
+
+// parseCBORTimestamps extracts the earliest and latest valid timestamps from CBOR product timestamps
+func (fw *FileWatcher) parseCBORTimestamps(timestamps []interface{}) (TimestampRange, error) {
+	if len(timestamps) == 0 {
+		return TimestampRange{}, &ValidationError{Field: "timestamps", Reason: "no timestamps found in CBOR"}
 	}
 
-	// Find the earliest valid timestamp (skip -1 values which indicate missing data)
-	var earliestTime *time.Time
-	for _, ts := range product.Timestamps {
-		if timestamp, ok := ts.(float64); ok && timestamp != -1 {
-			t := time.Unix(int64(timestamp), 0)
+	// Find the earliest and latest valid timestamps (skip -1 values which indicate missing data)
+	var earliestTime, latestTime *time.Time
+	for _, ts := range timestamps {
+		if t, ok := toCBORTimestamp(ts); ok {
 			if earliestTime == nil || t.Before(*earliestTime) {
 				earliestTime = &t
 			}
+			if latestTime == nil || t.After(*latestTime) {
+				latestTime = &t
+			}
 		}
 	}
 
 	if earliestTime == nil {
-		return time.Time{}, fmt.Errorf("no valid timestamps found in CBOR")
+		return TimestampRange{}, &ValidationError{Field: "timestamps", Reason: "no valid timestamps found in CBOR"}
+	}
+
+	fw.logger.Debug().
+		Time("earliest_timestamp", *earliestTime).
+		Time("latest_timestamp", *latestTime).
+		Int("total_timestamps", len(timestamps)).
+		Msg("Extracted timestamp range from CBOR")
+	return TimestampRange{Start: *earliestTime, End: *latestTime}, nil
+}
+
+// toCBORTimestamp converts a decoded CBOR timestamp in Unix seconds to a time. SatDump writes floats, but
+// integers of any width and tag 1 (epoch-based date/time) are valid CBOR for the same value. The -1 marking
+// missing data and values that aren't timestamps return false.
+func toCBORTimestamp(v interface{}) (time.Time, bool) {
+	var seconds int64
+	switch ts := v.(type) {
+	case float64:
+		if math.IsNaN(ts) || math.IsInf(ts, 0) {
+			return time.Time{}, false
+		}
+		seconds = int64(ts)
+	case float32:
+		return toCBORTimestamp(float64(ts))
+	case int:
+		seconds = int64(ts)
+	case int64:
+		seconds = ts
+	case int32:
+		seconds = int64(ts)
+	case uint64:
+		if ts > math.MaxInt64 {
+			return time.Time{}, false
+		}
+		seconds = int64(ts)
+	case uint32:
+		seconds = int64(ts)
+	case cbor.Tag:
+		if ts.Number != 1 {
+			return time.Time{}, false
+		}
+		return toCBORTimestamp(ts.Content)
+	case time.Time:
+		// Tag 1 as decoded into interface{} by default
+		return ts, !ts.IsZero()
+	default:
+		return time.Time{}, false
 	}
 
-	fw.logger.Debug().Time("earliest_timestamp", *earliestTime).Int("total_timestamps", len(product.Timestamps)).Msg("Extracted earliest timestamp from CBOR")
-	return *earliestTime, nil
+	if seconds == -1 {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
 }
 
 // isCompleteSatellitePass checks if a directory contains a complete satellite pass
 func (fw *FileWatcher) isCompleteSatellitePass(dirPath string) bool {
+	// SatDump is still writing the pass
+	if findLockFile(dirPath) != "" {
+		return false
+	}
+
+	// The pipeline signals completion explicitly
+	if fw.config.CompletionSentinel != "" {
+		_, err := os.Stat(filepath.Join(dirPath, fw.config.CompletionSentinel))
+		return err == nil
+	}
+
 	// Check for dataset.json (main metadata file)
 	datasetPath := filepath.Join(dirPath, "dataset.json")
 	if _, err := os.Stat(datasetPath); os.IsNotExist(err) {
@@ -332,160 +923,1012 @@ func (fw *FileWatcher) isCompleteSatellitePass(dirPath string) bool {
 	return hasProductDir
 }
 
-// processSatellitePass processes a complete satellite pass directory
-func (fw *FileWatcher) processSatellitePass(dirPath string) error {
-	fw.logger.Info().Str("dir", dirPath).Msg("Processing satellite pass")
+// PassResult describes the outcome of processing a satellite pass
+type PassResult struct {
+	Satellite  string
+	PostID     string   // Empty if no post was created
+	ImageCount int      // Images uploaded successfully
+	FailedStep string   // First step that failed, see step*
+	Uploaded   []string // Files attached to the post, relative to the pass directory
+}
 
-	// Read dataset.json for main metadata
-	datasetPath := filepath.Join(dirPath, "dataset.json")
-	dataset, err := fw.parseJSONFile(datasetPath)
-	if err != nil {
-		return fmt.Errorf("failed to parse dataset.json: %w", err)
-	}
+// processPass processes a satellite pass directory and records the outcome in the pass history and retry queue
+func (fw *FileWatcher) processPass(dirPath string) error {
+	start := time.Now()
+	result, err := fw.processSatellitePass(dirPath)
+	fw.recordPass(dirPath, start, result, err)
+	fw.updateRetryQueue(dirPath, result, err)
+	return err
+}
 
-	// Check for CADU files in root directory
-	var caduPaths []string
-	caduGlob := filepath.Join(dirPath, "*.cadu")
-	if matches, err := filepath.Glob(caduGlob); err == nil && len(matches) > 0 {
-		caduPaths = matches
-		fw.logger.Debug().Int("count", len(caduPaths)).Msg("Found CADU files")
+// updateRetryQueue removes a pass from the retry queue once it succeeded, or records the failed attempt
+func (fw *FileWatcher) updateRetryQueue(dirPath string, result *PassResult, err error) {
+	if fw.retries == nil || fw.config.DryRun {
+		return
 	}
 
-	// Always check for CBOR and images, regardless of CADU presence
-	var selectedProduct string
-	var cborPath string
-	var imagePaths []string
+	if err == nil {
+		if err := fw.retries.Remove(dirPath); err != nil {
+			fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to remove pass from retry queue")
+		}
+		return
+	}
 
-	// Find product directories and collect files
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+	// Skipping for lack of disk space is not an attempt
+	if errors.Is(err, ErrLowDiskSpace) {
+		return
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	entry, ok := fw.retries.Get(dirPath)
+	if !ok {
+		entry = &RetryEntry{Path: dirPath}
+	}
+	// A rejected token is a config problem, the progress is kept but the attempt doesn't count
+	// towards MaxRetries so the pass isn't dead-lettered before the token is fixed
+	if IsAuthError(err) {
+		fw.logger.Error().Err(err).Str("dir", dirPath).Msg("Station token was rejected, check the token in the config file")
+	} else {
+		entry.AttemptCount++
+	}
+	entry.LastAttempt = time.Now()
+	entry.LastError = err.Error()
+	entry.FailedStep = result.FailedStep
+	entry.PostID = result.PostID
+	entry.Uploaded = result.Uploaded
+	entry.History = append(entry.History, RetryAttempt{Time: entry.LastAttempt, Step: entry.FailedStep, Error: entry.LastError})
+	if err := fw.retries.Save(entry); err != nil {
+		fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to save pass to retry queue")
+		return
+	}
 
-		potentialProductDir := filepath.Join(dirPath, entry.Name())
-		cborFile := filepath.Join(potentialProductDir, "product.cbor")
+	fw.logger.Warn().
+		Str("dir", dirPath).
+		Str("failed_step", entry.FailedStep).
+		Int("attempt", entry.AttemptCount).
+		Int("max_retries", fw.config.MaxRetries).
+		Msg("Queued satellite pass for retry")
+}
 
-		// Check if this directory contains a CBOR file
-		if _, err := os.Stat(cborFile); err == nil {
-			// Found a product directory with CBOR
-			if selectedProduct == "" {
-				selectedProduct = entry.Name()
-				cborPath = cborFile
-			}
+// retriesExhausted reports whether a pass has failed MaxRetries times
+func (fw *FileWatcher) retriesExhausted(dirPath string) bool {
+	if fw.retries == nil || fw.config.MaxRetries <= 0 {
+		return false
+	}
+	entry, ok := fw.retries.Get(dirPath)
+	return ok && entry.AttemptCount >= fw.config.MaxRetries
+}
 
-			// Collect all PNG images from this product directory
-			productEntries, err := os.ReadDir(potentialProductDir)
-			if err != nil {
-				fw.logger.Warn().Err(err).Str("dir", potentialProductDir).Msg("Failed to read product directory")
-				continue
-			}
+// skipPass sets aside a pass that failed in a way retrying won't fix. Queued passes go to the
+// dead-letter directory right away, others stay marked as processed until the next restart.
+func (fw *FileWatcher) skipPass(dirPath string, err error) {
+	event := fw.logger.Warn().Err(err).Str("dir", dirPath)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		event = event.Int("status", apiErr.StatusCode)
+	}
+	event.Msg("Satellite pass failed permanently, not retrying it")
 
-			for _, productEntry := range productEntries {
-				if strings.HasSuffix(productEntry.Name(), ".png") {
-					imagePaths = append(imagePaths, filepath.Join(potentialProductDir, productEntry.Name()))
-				}
-			}
+	if fw.retries == nil || fw.config.DryRun {
+		return
+	}
+	if _, ok := fw.retries.Get(dirPath); ok {
+		fw.moveToDeadLetter(dirPath)
+	}
+}
+
+// pruneRetryQueue drops queued passes whose directory no longer exists
+func (fw *FileWatcher) pruneRetryQueue() {
+	entries := fw.retries.Entries()
+	for _, entry := range entries {
+		if _, err := os.Stat(entry.Path); os.IsNotExist(err) {
+			fw.logger.Debug().Str("dir", entry.Path).Msg("Queued satellite pass no longer exists, dropping it")
+			fw.retries.Remove(entry.Path)
 		}
 	}
+	if len(entries) > 0 {
+		fw.logger.Info().Int("count", len(fw.retries.Entries())).Msg("Loaded retry queue")
+	}
+}
 
-	if selectedProduct != "" {
-		fw.logger.Info().Str("product", selectedProduct).Int("images", len(imagePaths)).Msg("Found product with CBOR and images")
+// recordPass adds a processed pass to the pass history
+func (fw *FileWatcher) recordPass(dirPath string, start time.Time, result *PassResult, err error) {
+	if fw.history == nil || fw.config.DryRun {
+		return
 	}
 
-	if len(caduPaths) > 0 {
-		fw.logger.Info().Int("cadu_files", len(caduPaths)).Msg("Processing CADU files")
+	record := PassRecord{
+		Path:        dirPath,
+		ProcessedAt: start,
+		DurationMS:  time.Since(start).Milliseconds(),
+		Success:     err == nil,
+	}
+	if result != nil {
+		record.Satellite = result.Satellite
+		record.PostID = result.PostID
+		record.ImageCount = result.ImageCount
 	}
+	if err := fw.history.RecordPass(record); err != nil {
+		fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to record pass history")
+	}
+}
 
-	// Determine the timestamp to use for the post
-	// Prefer CBOR timestamps over dataset.json processing timestamp
-	postTimestamp := dataset.Timestamp
-	if cborPath != "" {
-		if cborTimestamp, err := fw.parseCBORTimestamps(cborPath); err != nil {
-			fw.logger.Warn().Err(err).Str("cbor", cborPath).Msg("Failed to parse CBOR timestamps, falling back to dataset.json timestamp")
-		} else {
-			postTimestamp = cborTimestamp
-			fw.logger.Info().Time("cbor_timestamp", cborTimestamp).Time("dataset_timestamp", dataset.Timestamp).Msg("Using CBOR timestamp instead of dataset.json timestamp")
+// WatchPathCount returns the number of watched directories
+func (fw *FileWatcher) WatchPathCount() int {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return len(fw.config.WatchPaths)
+}
+
+// ProcessedPassCount returns the number of passes below the watch paths that were uploaded successfully,
+// the history is shared by all stations so passes of other watch paths are not counted
+func (fw *FileWatcher) ProcessedPassCount() (int, error) {
+	if fw.history == nil {
+		return 0, fmt.Errorf("pass history is not available")
+	}
+
+	fw.mu.Lock()
+	watchPaths := append([]string(nil), fw.config.WatchPaths...)
+	fw.mu.Unlock()
+
+	// Passes are recorded below the watch path as configured, which may be relative
+	prefixes := make([]string, 0, 2*len(watchPaths))
+	for _, watchPath := range watchPaths {
+		prefixes = append(prefixes, filepath.Clean(watchPath))
+		if abs, err := filepath.Abs(watchPath); err == nil && abs != filepath.Clean(watchPath) {
+			prefixes = append(prefixes, abs)
 		}
 	}
+	return fw.history.CountPasses(true, prefixes)
+}
 
-	// Create post with metadata
-	postReq := PostRequest{
-		Timestamp:     postTimestamp.Format(time.RFC3339),
-		SatelliteName: dataset.SatelliteName,
-		Metadata:      fw.mapToJSON(dataset.Metadata),
+// processSatellitePass processes a complete satellite pass directory, the result is
+// filled in as far as processing got and is never nil
+func (fw *FileWatcher) processSatellitePass(dirPath string) (*PassResult, error) {
+	fw.logger.Info().Str("dir", dirPath).Msg("Processing satellite pass")
+	result := &PassResult{FailedStep: stepPrepare}
+
+	// Resume an earlier failed attempt, files it uploaded are skipped
+	retry, resuming := fw.lookupRetry(dirPath)
+	if resuming {
+		result.Uploaded = retry.Uploaded
 	}
 
-	post, err := fw.apiClient.CreatePost(postReq)
+	// Uploads are drained by Stop on shutdown rather than cancelled
+	ctx := context.Background()
+
+	// SatDump may have written an incomplete pass if the partition filled up
+	if err := fw.checkDiskSpace(dirPath); err != nil {
+		return result, err
+	}
+
+	plan, err := fw.preparePass(dirPath)
 	if err != nil {
-		return fmt.Errorf("failed to create post: %w", err)
+		return result, err
+	}
+	postReq := plan.PostRequest
+	result.Satellite = postReq.SatelliteName
+
+	// Run pre-upload hook, a failure aborts the upload
+	hookEnv := map[string]string{
+		"SATHUB_DIR":       dirPath,
+		"SATHUB_SATELLITE": postReq.SatelliteName,
+		"SATHUB_TIMESTAMP": postReq.Timestamp,
+	}
+	if fw.config.PreUploadHook != "" {
+		if err := runHook(fw.config.PreUploadHook, hookEnv); err != nil {
+			result.FailedStep = stepPreUploadHook
+			return result, fmt.Errorf("pre-upload hook: %w", err)
+		}
+		fw.logger.Debug().Str("dir", dirPath).Msg("Pre-upload hook completed")
+	}
+
+	// Reuse the post if this pass was already created, e.g. before a restart
+	var post *PostResponse
+	if resuming && retry.PostID != "" {
+		post = &PostResponse{ID: retry.PostID, SatelliteName: postReq.SatelliteName}
+		fw.logger.Info().
+			Str("post_id", post.ID).
+			Str("failed_step", retry.FailedStep).
+			Int("attempt", retry.AttemptCount+1).
+			Msg("Resuming failed upload with existing post")
+	} else if postID, ok := fw.lookupChecksum(postReq.IdempotencyKey); ok {
+		post = &PostResponse{ID: postID, SatelliteName: postReq.SatelliteName}
+		fw.logger.Info().Str("post_id", post.ID).Str("satellite", post.SatelliteName).Msg("Pass was already created, reusing existing post")
+	} else {
+		post, err = fw.apiClient.CreatePost(ctx, postReq)
+		if err != nil {
+			result.FailedStep = stepCreatePost
+			return result, fmt.Errorf("failed to create post: %w", err)
+		}
+
+		fw.logger.Info().Str("post_id", post.ID).Str("satellite", post.SatelliteName).Msg("Created post")
+		fw.saveChecksum(postReq.IdempotencyKey, post.ID)
 	}
 
-	fw.logger.Info().Str("post_id", post.ID).Str("satellite", post.SatelliteName).Msg("Created post")
+	result.PostID = post.ID
+	result.FailedStep = ""
 
 	// Upload CADU files if present
-	for _, caduPath := range caduPaths {
-		if err := fw.apiClient.UploadCADU(post.ID, caduPath); err != nil {
+	var uploadErrs uploadFailures
+	for _, caduPath := range plan.CADUPaths {
+		if fw.alreadyUploaded(dirPath, caduPath, retry) {
+			continue
+		}
+		start := time.Now()
+		err := fw.apiClient.UploadCADU(ctx, post.ID, caduPath)
+		fw.metrics.ObserveUpload(metrics.UploadTypeCADU, time.Since(start))
+		if err != nil {
+			uploadErrs = append(uploadErrs, err)
+			result.failStep(stepUploadCADU)
 			fw.logger.Warn().Err(err).Str("cadu", caduPath).Msg("Failed to upload CADU")
 			// Continue with other uploads
 		} else {
+			result.addUploaded(dirPath, caduPath)
 			fw.logger.Info().Str("cadu", filepath.Base(caduPath)).Str("post_id", post.ID).Msg("Uploaded CADU")
 		}
 	}
 
-	// Upload CBOR file if present
-	if cborPath != "" {
-		if err := fw.apiClient.UploadCBOR(post.ID, cborPath); err != nil {
+	// Upload IQ recordings after the CADU files
+	for _, iqPath := range plan.IQPaths {
+		if fw.alreadyUploaded(dirPath, iqPath, retry) {
+			continue
+		}
+		start := time.Now()
+		err := fw.apiClient.UploadIQ(ctx, post.ID, iqPath)
+		fw.metrics.ObserveUpload(metrics.UploadTypeIQ, time.Since(start))
+		if err != nil {
+			uploadErrs = append(uploadErrs, err)
+			result.failStep(stepUploadIQ)
+			fw.logger.Warn().Err(err).Str("iq", iqPath).Msg("Failed to upload IQ recording")
+		} else {
+			result.addUploaded(dirPath, iqPath)
+			fw.logger.Info().Str("iq", filepath.Base(iqPath)).Str("post_id", post.ID).Msg("Uploaded IQ recording")
+		}
+	}
+
+	// Upload the CBOR file of every product
+	for _, cborPath := range plan.CBORPaths {
+		if fw.alreadyUploaded(dirPath, cborPath, retry) {
+			continue
+		}
+		start := time.Now()
+		err := fw.apiClient.UploadCBOR(ctx, post.ID, cborPath)
+		fw.metrics.ObserveUpload(metrics.UploadTypeCBOR, time.Since(start))
+		if errors.Is(err, ErrInvalidCBOR) {
+			// Retrying would not repair the file, upload the rest of the pass without it
+			fw.logger.Warn().Err(err).Str("cbor", cborPath).Msg("Skipping CBOR upload, file could not be decoded")
+		} else if err != nil {
+			uploadErrs = append(uploadErrs, err)
+			result.failStep(stepUploadCBOR)
 			fw.logger.Warn().Err(err).Str("cbor", cborPath).Msg("Failed to upload CBOR")
 			// Continue with image uploads even if CBOR fails
 		} else {
+			result.addUploaded(dirPath, cborPath)
 			fw.logger.Info().Str("cbor", filepath.Base(cborPath)).Str("post_id", post.ID).Msg("Uploaded CBOR")
 		}
 	}
 
 	// Upload all images
-	for _, imagePath := range imagePaths {
-		if err := fw.apiClient.UploadImage(post.ID, imagePath); err != nil {
+	for _, imagePath := range plan.ImagePaths {
+		if fw.alreadyUploaded(dirPath, imagePath, retry) {
+			result.ImageCount++
+			continue
+		}
+		start := time.Now()
+		err := fw.apiClient.UploadImage(ctx, post.ID, imagePath, plan.ImageProducts[imagePath])
+		fw.metrics.ObserveUpload(metrics.UploadTypeImage, time.Since(start))
+		if err != nil {
+			uploadErrs = append(uploadErrs, err)
+			result.failStep(stepUploadImages)
 			fw.logger.Warn().Err(err).Str("image", imagePath).Msg("Failed to upload image")
 			// Continue with other images
 		} else {
-			fw.logger.Info().Str("image", filepath.Base(imagePath)).Str("post_id", post.ID).Msg("Uploaded image")
+			result.ImageCount++
+			result.addUploaded(dirPath, imagePath)
+			fw.logger.Info().Str("image", filepath.Base(imagePath)).Str("product", plan.ImageProducts[imagePath]).Str("post_id", post.ID).Msg("Uploaded image")
+		}
+	}
+
+	if plan.SkippedImages > 0 {
+		fw.logger.Warn().Int("skipped_images", plan.SkippedImages).Str("dir", dirPath).Msg("Corrupt images were not uploaded")
+	}
+
+	// Leave the pass in place so it is retried, uploaded files are skipped next time
+	if len(uploadErrs) > 0 {
+		return result, uploadErrs
+	}
+
+	fw.metrics.PassProcessed(postReq.SatelliteName)
+
+	// Run post-upload hook once all uploads succeeded, failures don't affect processing
+	if fw.config.PostUploadHook != "" {
+		hookEnv["SATHUB_POST_ID"] = post.ID
+		if err := runHook(fw.config.PostUploadHook, hookEnv); err != nil {
+			fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Post-upload hook failed")
+		} else {
+			fw.logger.Debug().Str("dir", dirPath).Msg("Post-upload hook completed")
 		}
 	}
 
 	// Send health check
-	if healthResp, err := fw.apiClient.StationHealth(); err != nil {
+	if healthResp, err := fw.apiClient.StationHealth(ctx); err != nil {
 		fw.logger.Warn().Err(err).Msg("Failed to send health check")
+		fw.metrics.HealthCheckError()
 	} else {
 		// Update config with server settings
 		fw.config.UpdateFromServerSettings(healthResp.Settings)
 	}
 
+	return result, nil
+}
+
+// passPlan is what processing a pass sends to the API, the post and the files attached to it
+type passPlan struct {
+	PostRequest   PostRequest
+	CADUPaths     []string
+	IQPaths       []string
+	CBORPaths     []string // product.cbor of each product directory
+	ImagePaths    []string
+	ImageProducts map[string]string // image path -> product directory name
+	SkippedImages int               // corrupt images left out by image validation
+}
+
+// preparePass reads the metadata of the pass in dirPath and collects the files to upload without contacting the API
+func (fw *FileWatcher) preparePass(dirPath string) (*passPlan, error) {
+	// Read dataset.json for main metadata
+	datasetPath := filepath.Join(dirPath, "dataset.json")
+	dataset, err := fw.parseJSONFile(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map inconsistent SatDump names to their canonical form
+	if normalized := NormalizeSatelliteName(dataset.SatelliteName, fw.config.GetSatelliteAliases()); normalized != dataset.SatelliteName {
+		fw.logger.Info().Str("raw", dataset.SatelliteName).Str("satellite", normalized).Msg("Normalized satellite name")
+		dataset.SatelliteName = normalized
+	}
+
+	// Check for CADU files in root directory
+	var caduPaths []string
+	caduGlob := filepath.Join(dirPath, "*.cadu")
+	if matches, err := filepath.Glob(caduGlob); err == nil && len(matches) > 0 {
+		caduPaths = matches
+		fw.logger.Debug().Int("count", len(caduPaths)).Msg("Found CADU files")
+	}
+
+	// Raw I/Q recordings are only collected when enabled, they can be several gigabytes
+	var iqPaths []string
+	if fw.config.UploadIQ {
+		for _, ext := range fw.config.IQExtensions {
+			if matches, err := filepath.Glob(filepath.Join(dirPath, "*"+ext)); err == nil {
+				iqPaths = append(iqPaths, matches...)
+			}
+		}
+		if len(iqPaths) > 0 {
+			fw.logger.Debug().Int("count", len(iqPaths)).Msg("Found IQ recordings")
+		}
+	}
+
+	// Always check for CBOR and images, regardless of CADU presence
+	var products []string
+	var cborPaths []string // product.cbor of each product, parallel to products
+	var imagePaths []string
+	imageProducts := make(map[string]string) // image path -> product directory name
+
+	// Find product directories and collect files
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		potentialProductDir := filepath.Join(dirPath, entry.Name())
+		cborFile := filepath.Join(potentialProductDir, "product.cbor")
+
+		// Check if this directory contains a CBOR file
+		if _, err := os.Stat(cborFile); err == nil {
+			// Found a product directory with CBOR, passes can have one per instrument
+			products = append(products, entry.Name())
+			cborPaths = append(cborPaths, cborFile)
+
+			// Collect all PNG and GeoTIFF images from this product directory
+			productEntries, err := os.ReadDir(potentialProductDir)
+			if err != nil {
+				fw.logger.Warn().Err(err).Str("dir", potentialProductDir).Msg("Failed to read product directory")
+				continue
+			}
+
+			for _, productEntry := range productEntries {
+				if isImageFile(productEntry.Name()) {
+					imagePath := filepath.Join(potentialProductDir, productEntry.Name())
+					imagePaths = append(imagePaths, imagePath)
+					imageProducts[imagePath] = entry.Name()
+				}
+			}
+		}
+	}
+
+	imagePaths = fw.filterImages(imagePaths)
+
+	// Broken images are accepted by the server but can't be displayed, leave them out
+	skippedImages := 0
+	if fw.config.ValidateImages {
+		imagePaths, skippedImages = fw.validateImages(imagePaths)
+	}
+
+	if len(products) > 0 {
+		fw.logger.Info().Strs("products", products).Int("images", len(imagePaths)).Msg("Found products with CBOR and images")
+	}
+
+	if len(caduPaths) > 0 {
+		fw.logger.Info().Int("cadu_files", len(caduPaths)).Msg("Processing CADU files")
+	}
+
+	// Parse CBORs for timestamps, TLE and instrument. The timestamps of all products are
+	// combined, TLE and instrument are taken from the first product that has them.
+	var cborResult *CBORResult
+	for _, cborPath := range cborPaths {
+		parsed, err := fw.parseCBOR(cborPath)
+		if err != nil {
+			fw.logger.Warn().Err(err).Str("cbor", cborPath).Msg("Failed to parse CBOR product")
+			continue
+		}
+		if cborResult == nil {
+			cborResult = parsed
+			continue
+		}
+		cborResult.Timestamps.extend(parsed.Timestamps)
+		if cborResult.TLE1 == "" || cborResult.TLE2 == "" {
+			cborResult.TLE1, cborResult.TLE2 = parsed.TLE1, parsed.TLE2
+		}
+		if cborResult.Instrument == "" {
+			cborResult.Instrument = parsed.Instrument
+		}
+	}
+
+	// Determine the timestamp to use for the post
+	// Prefer CBOR timestamps over dataset.json processing timestamp
+	postTimestamp := dataset.Timestamp
+	var duration time.Duration
+	if cborResult != nil && !cborResult.Timestamps.Start.IsZero() {
+		postTimestamp = cborResult.Timestamps.Start
+		fw.logger.Info().Time("cbor_timestamp", cborResult.Timestamps.Start).Time("dataset_timestamp", dataset.Timestamp).Msg("Using CBOR timestamp instead of dataset.json timestamp")
+
+		if !cborResult.Timestamps.End.IsZero() {
+			duration = cborResult.Timestamps.End.Sub(cborResult.Timestamps.Start)
+			fw.logger.Debug().Dur("duration", duration).Msg("Calculated pass duration")
+		}
+	} else if len(cborPaths) > 0 {
+		fw.logger.Warn().Strs("products", products).Msg("No usable CBOR timestamp, falling back to dataset.json timestamp")
+	}
+
+	// Include TLE and instrument in metadata so they reach the backend
+	if cborResult != nil {
+		if cborResult.TLE1 != "" && cborResult.TLE2 != "" {
+			dataset.Metadata["tle"] = map[string]interface{}{
+				"line1": cborResult.TLE1,
+				"line2": cborResult.TLE2,
+			}
+		}
+		if cborResult.Instrument != "" {
+			dataset.Metadata["instrument"] = cborResult.Instrument
+		}
+	}
+
+	// Create post with metadata
+	postReq := PostRequest{
+		Timestamp:       postTimestamp.Format(time.RFC3339),
+		SatelliteName:   dataset.SatelliteName,
+		Metadata:        fw.mapToJSON(SanitizeMetadata(dataset.Metadata, fw.config.MetadataMaxValueLen)),
+		DurationSeconds: int(duration.Seconds()),
+	}
+	if checksum, err := fileChecksum(datasetPath); err != nil {
+		fw.logger.Warn().Err(err).Msg("Failed to checksum dataset.json")
+	} else {
+		postReq.IdempotencyKey = checksum
+	}
+	if norad, ok := dataset.Metadata["norad"].(float64); ok {
+		postReq.NORAD = int(norad)
+	}
+	if frequency, ok := dataset.Metadata["frequency"].(float64); ok {
+		postReq.FrequencyMHz = frequency
+	}
+	if cborResult != nil {
+		postReq.TLE1 = cborResult.TLE1
+		postReq.TLE2 = cborResult.TLE2
+		postReq.Instrument = cborResult.Instrument
+	}
+
+	return &passPlan{
+		PostRequest:   postReq,
+		CADUPaths:     caduPaths,
+		IQPaths:       iqPaths,
+		CBORPaths:     cborPaths,
+		ImagePaths:    imagePaths,
+		ImageProducts: imageProducts,
+		SkippedImages: skippedImages,
+	}, nil
+}
+
+// checkDiskSpace logs a warning when the partition holding dirPath is low on space,
+// and returns an error when it is below half the minimum so the upload is skipped
+func (fw *FileWatcher) checkDiskSpace(dirPath string) error {
+	freeMB, err := CheckDiskSpace(dirPath, fw.config.MinFreeDiskMB)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrLowDiskSpace) {
+		fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to check disk space")
+		return nil
+	}
+
+	fw.logger.Warn().
+		Int64("free_mb", freeMB).
+		Int64("min_free_mb", fw.config.MinFreeDiskMB).
+		Msg("Low disk space on watch partition")
+
+	if freeMB >= fw.config.MinFreeDiskMB/2 {
+		return nil
+	}
+
+	if fw.onDiskSpaceLow != nil {
+		fw.onDiskSpaceLow(freeMB)
+	}
+	return fmt.Errorf("skipping upload: %w", err)
+}
+
+// lookupRetry returns the retry queue entry of an earlier failed attempt for dirPath
+func (fw *FileWatcher) lookupRetry(dirPath string) (*RetryEntry, bool) {
+	if fw.retries == nil || fw.config.DryRun {
+		return nil, false
+	}
+	return fw.retries.Get(dirPath)
+}
+
+// alreadyUploaded reports whether filePath was uploaded by the earlier attempt in retry
+func (fw *FileWatcher) alreadyUploaded(dirPath, filePath string, retry *RetryEntry) bool {
+	if retry == nil {
+		return false
+	}
+	rel, err := filepath.Rel(dirPath, filePath)
+	if err != nil || !retry.hasUploaded(rel) {
+		return false
+	}
+	fw.logger.Debug().Str("file", rel).Msg("Already uploaded by an earlier attempt, skipping")
+	return true
+}
+
+// failStep records step as the failed step unless an earlier step already failed
+func (r *PassResult) failStep(step string) {
+	if r.FailedStep == "" {
+		r.FailedStep = step
+	}
+}
+
+// addUploaded records filePath as attached to the post
+func (r *PassResult) addUploaded(dirPath, filePath string) {
+	if rel, err := filepath.Rel(dirPath, filePath); err == nil {
+		r.Uploaded = append(r.Uploaded, rel)
+	}
+}
+
+// lookupChecksum returns the post previously created for a dataset checksum
+func (fw *FileWatcher) lookupChecksum(checksum string) (string, bool) {
+	if fw.checksums == nil || checksum == "" || fw.config.DryRun || fw.forceNewPosts {
+		return "", false
+	}
+	return fw.checksums.Lookup(checksum)
+}
+
+// saveChecksum records the post created for a dataset checksum
+func (fw *FileWatcher) saveChecksum(checksum, postID string) {
+	if fw.checksums == nil || checksum == "" || fw.config.DryRun {
+		return
+	}
+	if err := fw.checksums.Save(checksum, postID); err != nil {
+		fw.logger.Warn().Err(err).Str("post_id", postID).Msg("Failed to save upload checksum")
+	}
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of a file
+func fileChecksum(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// processedNameData is the data available to the processed_naming template
+type processedNameData struct {
+	Name      string // original directory name
+	Satellite string // normalized satellite name from dataset.json
+	Year      string
+	Month     string // zero-padded, e.g. 03
+	Day       string // zero-padded, e.g. 09
+}
+
+// processedDestination returns the path dirPath is moved to, rendered from the processed_naming template.
+// The plain directory name is used when the template fails or points outside the processed directory.
+func (fw *FileWatcher) processedDestination(dirPath string) string {
+	name := filepath.Base(dirPath)
+	dest := filepath.Join(fw.config.ProcessedDir, name)
+	naming := fw.config.ProcessedNaming
+	if naming == "" || naming == "{{.Name}}" {
+		return dest
+	}
+
+	tmpl, err := template.New("processed_naming").Parse(naming)
+	if err != nil {
+		fw.logger.Warn().Err(err).Str("processed_naming", naming).Msg("Invalid processed_naming template, using directory name")
+		return dest
+	}
+
+	data := processedNameData{Name: name, Satellite: "Unknown"}
+	timestamp := time.Now()
+	if dataset, err := fw.parseJSONFile(filepath.Join(dirPath, "dataset.json")); err == nil {
+		data.Satellite = NormalizeSatelliteName(dataset.SatelliteName, fw.config.GetSatelliteAliases())
+		timestamp = dataset.Timestamp
+	}
+	// Satellite names such as "METEOR-M2 3/4" must not create extra directory levels
+	data.Satellite = strings.NewReplacer("/", "_", "\\", "_").Replace(data.Satellite)
+	timestamp = timestamp.UTC()
+	data.Year = timestamp.Format("2006")
+	data.Month = timestamp.Format("01")
+	data.Day = timestamp.Format("02")
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		fw.logger.Warn().Err(err).Str("processed_naming", naming).Msg("Failed to render processed_naming template, using directory name")
+		return dest
+	}
+	templated := filepath.Join(fw.config.ProcessedDir, filepath.FromSlash(rendered.String()))
+	if !isInsideDir(templated, fw.config.ProcessedDir) {
+		fw.logger.Warn().Str("processed_naming", naming).Str("dest", templated).Msg("processed_naming points outside the processed directory, using directory name")
+		return dest
+	}
+	return templated
+}
+
+// moveDirectoryToProcessed moves a processed directory to the processed location and returns where it went.
+// A failure to compress the moved directory is only logged, the pass has left the watch directory then.
+func (fw *FileWatcher) moveDirectoryToProcessed(dirPath string) (string, error) {
+	dest := fw.processedDestination(dirPath)
+
+	if fw.config.DryRun {
+		fw.logger.Info().Str("from", dirPath).Str("to", dest).Msg("[dry-run] Would move directory to processed")
+		return dest, nil
+	}
+
+	// The naming template can place passes in subdirectories
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create processed directory: %w", err)
+	}
+	// A pass with the same name may have been processed before, e.g. re-recorded or restored from the dead-letter directory
+	if unique := uniqueDestination(dest, fw.config.CompressProcessed, time.Now()); unique != dest {
+		fw.logger.Warn().
+			Str("dir", dirPath).
+			Str("existing", dest).
+			Str("to", unique).
+			Msg("Processed directory already contains a pass with this name, keeping both")
+		dest = unique
+	}
+	if err := moveDirectory(dirPath, dest); err != nil {
+		return "", fmt.Errorf("failed to move %s to %s: %w", dirPath, dest, err)
+	}
+
+	if fw.config.CompressProcessed {
+		archivePath, err := compressProcessedDirectory(fw.config.ProcessedDir, dest)
+		if err != nil {
+			fw.logger.Warn().Err(err).Str("dir", dest).Msg("Failed to compress processed directory")
+			return dest, nil
+		}
+		fw.logger.Debug().Str("archive", archivePath).Msg("Compressed processed directory")
+		return archivePath, nil
+	}
+	return dest, nil
+}
+
+// uniqueDestination returns dest, or dest with _<unix timestamp> appended when dest is taken.
+// With archive set, dest is also taken when its .tar.gz archive exists.
+func uniqueDestination(dest string, archive bool, now time.Time) string {
+	taken := func(path string) bool {
+		if _, err := os.Lstat(path); err == nil {
+			return true
+		}
+		if archive {
+			if _, err := os.Lstat(path + ".tar.gz"); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !taken(dest) {
+		return dest
+	}
+	unique := fmt.Sprintf("%s_%d", dest, now.Unix())
+	// Another collision within the same second
+	for i := 2; taken(unique); i++ {
+		unique = fmt.Sprintf("%s_%d_%d", dest, now.Unix(), i)
+	}
+	return unique
+}
+
+// moveToDeadLetter moves a pass that failed MaxRetries times to the dead-letter directory,
+// together with a failure.json holding its retry history
+func (fw *FileWatcher) moveToDeadLetter(dirPath string) {
+	entry, _ := fw.retries.Get(dirPath)
+	fw.deadLetter(dirPath, entry)
+}
+
+// deadLetter moves a pass to the dead-letter directory and writes entry to its failure.json
+func (fw *FileWatcher) deadLetter(dirPath string, entry *RetryEntry) {
+	// A pass with the same name may have failed before, e.g. after a dead-letter retry
+	dest := uniqueDestination(filepath.Join(fw.config.DeadLetterDir, filepath.Base(dirPath)), false, time.Now())
+
+	fw.logger.Error().
+		Str("dir", dirPath).
+		Int("attempts", entry.AttemptCount).
+		Str("last_error", entry.LastError).
+		Str("dead_letter", dest).
+		Msg("Moving satellite pass to dead-letter directory")
+
+	if fw.config.DryRun {
+		return
+	}
+
+	if err := os.MkdirAll(fw.config.DeadLetterDir, 0755); err != nil {
+		fw.logger.Error().Err(err).Msg("Failed to create dead-letter directory")
+		return
+	}
+	if err := moveDirectory(dirPath, dest); err != nil {
+		fw.logger.Error().Err(err).Str("from", dirPath).Str("to", dest).Msg("Failed to move directory to dead-letter directory")
+		return
+	}
+
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dest, deadLetterFailureFile), content, 0644)
+	}
+	if err != nil {
+		fw.logger.Warn().Err(err).Str("dir", dest).Msg("Failed to write failure.json")
+	}
+
+	if fw.retries == nil {
+		return
+	}
+	if err := fw.retries.Remove(dirPath); err != nil {
+		fw.logger.Warn().Err(err).Str("dir", dirPath).Msg("Failed to remove pass from retry queue")
+	}
+}
+
+// renameFunc renames files and directories, replaced in tests to simulate moves across filesystems
+var renameFunc = os.Rename
+
+// moveDirectory renames src to dst, copying and deleting it when they are on different filesystems
+func moveDirectory(src, dst string) error {
+	err := renameFunc(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	logger.Info().Str("from", src).Str("to", dst).Msg("Destination is on another filesystem, copying")
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy directory: %w", err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		logger.Warn().Err(err).Str("dir", src).Msg("Failed to remove directory after copying")
+	}
 	return nil
 }
 
-// moveDirectoryToProcessed moves a processed directory to the processed location
-func (fw *FileWatcher) moveDirectoryToProcessed(dirPath string) {
-	dirName := filepath.Base(dirPath)
-	dest := filepath.Join(fw.config.ProcessedDir, dirName)
+// copyDir recursively copies src to dst, preserving file modes and syncing each file to disk
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			// The umask applies to MkdirAll
+			return os.Chmod(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFileSync(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFileSync copies a single file with the given mode and fsyncs it before returning
+func copyFileSync(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
 
-	if err := os.Rename(dirPath, dest); err != nil {
-		fw.logger.Warn().Err(err).Str("from", dirPath).Str("to", dest).Msg("Failed to move directory to processed")
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	// The umask applies to OpenFile
+	if err := out.Chmod(mode); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
 	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
-// mapToJSON converts a map to JSON string
+// postFields lists metadata keys that are sent as first-class PostRequest fields
+// and are therefore excluded from the free-form metadata blob
+var postFields = []string{"norad", "frequency"}
+
+// isSatelliteAllowed checks the pass satellite against the configured include and exclude filters
+func (fw *FileWatcher) isSatelliteAllowed(dirPath string) bool {
+	if len(fw.config.IncludeSatellites) == 0 && len(fw.config.ExcludeSatellites) == 0 {
+		return true
+	}
+
+	dataset, err := fw.parseJSONFile(filepath.Join(dirPath, "dataset.json"))
+	if err != nil {
+		// Let processing report the parse error
+		return true
+	}
+	satellite := NormalizeSatelliteName(dataset.SatelliteName, fw.config.GetSatelliteAliases())
+
+	if len(fw.config.IncludeSatellites) > 0 && !matchesPattern(satellite, fw.config.IncludeSatellites) {
+		fw.logger.Debug().Str("satellite", satellite).Str("dir", dirPath).Msg("Satellite not in include list, skipping")
+		return false
+	}
+	if matchesPattern(satellite, fw.config.ExcludeSatellites) {
+		fw.logger.Debug().Str("satellite", satellite).Str("dir", dirPath).Msg("Satellite in exclude list, skipping")
+		return false
+	}
+
+	fw.logger.Debug().Str("satellite", satellite).Str("dir", dirPath).Msg("Satellite allowed by filters")
+	return true
+}
+
+// imageExtensions are the file extensions of the SatDump image products that are collected
+var imageExtensions = []string{".png", ".tif", ".tiff"}
+
+// isImageFile reports whether name has the extension of an image product
+func isImageFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, imageExt := range imageExtensions {
+		if strings.EqualFold(ext, imageExt) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterImages drops images whose file name doesn't match the include patterns or matches an exclude pattern
+func (fw *FileWatcher) filterImages(imagePaths []string) []string {
+	if len(fw.config.ImageInclude) == 0 && len(fw.config.ImageExclude) == 0 {
+		return imagePaths
+	}
+
+	var filtered []string
+	for _, imagePath := range imagePaths {
+		name := filepath.Base(imagePath)
+		if len(fw.config.ImageInclude) > 0 && !matchesPattern(name, fw.config.ImageInclude) {
+			fw.logger.Debug().Str("image", name).Msg("Image not in include patterns, skipping")
+			continue
+		}
+		if matchesPattern(name, fw.config.ImageExclude) {
+			fw.logger.Debug().Str("image", name).Msg("Image in exclude patterns, skipping")
+			continue
+		}
+		filtered = append(filtered, imagePath)
+	}
+	return filtered
+}
+
+// matchesPattern reports whether name matches any of the glob patterns (case-insensitive),
+// so *.png also matches the RGB.PNG some decoders write like isImageFile does
+func matchesPattern(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(strings.ToLower(pattern), name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeSatelliteName returns the canonical name for raw from aliases (keyed by lower-case name),
+// or raw unchanged if it has no alias
+func NormalizeSatelliteName(raw string, aliases map[string]string) string {
+	if canonical, ok := aliases[strings.ToLower(strings.TrimSpace(raw))]; ok {
+		return canonical
+	}
+	return raw
+}
+
+// mapToJSON converts a map to JSON string, excluding first-class post fields
 func (fw *FileWatcher) mapToJSON(data map[string]interface{}) string {
-	if jsonData, err := json.Marshal(data); err == nil {
+	filtered := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		filtered[key] = value
+	}
+	for _, key := range postFields {
+		delete(filtered, key)
+	}
+
+	if jsonData, err := json.Marshal(filtered); err == nil {
 		return string(jsonData)
 	}
 	return "{}"
 }
 
+// SanitizeMetadata returns a copy of m without byte slices and with strings longer than maxValueLen
+// bytes replaced by a placeholder, so embedded thumbnails or propagation results don't bloat the post.
+// Nested maps and arrays are sanitized as well, a maxValueLen of 0 or less keeps all strings.
+func SanitizeMetadata(m map[string]interface{}, maxValueLen int) map[string]interface{} {
+	sanitized := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if _, ok := value.([]byte); ok {
+			continue
+		}
+		sanitized[key] = sanitizeMetadataValue(value, maxValueLen)
+	}
+	return sanitized
+}
+
+// sanitizeMetadataValue sanitizes a single metadata value for SanitizeMetadata
+func sanitizeMetadataValue(value interface{}, maxValueLen int) interface{} {
+	switch v := value.(type) {
+	case string:
+		if maxValueLen > 0 && len(v) > maxValueLen {
+			return fmt.Sprintf("<truncated: %d bytes>", len(v))
+		}
+		return v
+	case map[string]interface{}:
+		return SanitizeMetadata(v, maxValueLen)
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]byte); ok {
+				continue
+			}
+			items = append(items, sanitizeMetadataValue(item, maxValueLen))
+		}
+		return items
+	default:
+		return v
+	}
+}
+
 // SatDumpProduct represents the structure of a SatDump CBOR product file
 type SatDumpProduct struct {
 	Instrument string                 `cbor:"instrument" json:"instrument"`
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WatcherStats is a snapshot of the pass counters of a FileWatcher
+type WatcherStats struct {
+	ProcessedCount  int64      `json:"processed_count"` // passes uploaded successfully since startup
+	FailedCount     int64      `json:"failed_count"`    // failed attempts, a pass that is retried counts once per attempt
+	SkippedCount    int64      `json:"skipped_count"`   // passes left out by the satellite filters
+	UptimeSeconds   int64      `json:"uptime_seconds"`
+	LastProcessedAt *time.Time `json:"last_processed_at,omitempty"`
+	LastFailedAt    *time.Time `json:"last_failed_at,omitempty"`
+	LastFailedError string     `json:"last_failed_error,omitempty"`
+}
+
+// passStats holds the counters behind WatcherStats, the counts are updated atomically
+type passStats struct {
+	processed int64
+	failed    int64
+	skipped   int64
+	startTime time.Time
+}
+
+// recordProcessed counts a successfully uploaded pass
+func (fw *FileWatcher) recordProcessed() {
+	atomic.AddInt64(&fw.stats.processed, 1)
+	now := time.Now()
+	fw.mu.Lock()
+	fw.lastProcessedAt = &now
+	fw.mu.Unlock()
+}
+
+// recordFailed counts a failed attempt to process a pass
+func (fw *FileWatcher) recordFailed(err error) {
+	atomic.AddInt64(&fw.stats.failed, 1)
+	now := time.Now()
+	fw.mu.Lock()
+	fw.lastFailedAt = &now
+	fw.lastFailedError = err.Error()
+	fw.mu.Unlock()
+}
+
+// recordSkipped counts a pass that was left out by the satellite filters
+func (fw *FileWatcher) recordSkipped() {
+	atomic.AddInt64(&fw.stats.skipped, 1)
+}
+
+// Stats returns the pass counters since the watcher was created
+func (fw *FileWatcher) Stats() WatcherStats {
+	stats := WatcherStats{
+		ProcessedCount: atomic.LoadInt64(&fw.stats.processed),
+		FailedCount:    atomic.LoadInt64(&fw.stats.failed),
+		SkippedCount:   atomic.LoadInt64(&fw.stats.skipped),
+		UptimeSeconds:  int64(time.Since(fw.stats.startTime).Seconds()),
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	stats.LastProcessedAt = fw.lastProcessedAt
+	stats.LastFailedAt = fw.lastFailedAt
+	stats.LastFailedError = fw.lastFailedError
+	return stats
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sathub-client/config"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/rs/zerolog"
+)
+
+func TestUniqueDestination(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	stamp := fmt.Sprint(now.Unix())
+
+	tests := []struct {
+		name     string
+		existing []string // Directories, or files when ending in .tar.gz, created in the processed directory
+		archive  bool
+		want     string
+	}{
+		{name: "free", want: "pass"},
+		{name: "taken", existing: []string{"pass"}, want: "pass_" + stamp},
+		{name: "same second", existing: []string{"pass", "pass_" + stamp}, want: "pass_" + stamp + "_2"},
+		{name: "same second twice", existing: []string{"pass", "pass_" + stamp, "pass_" + stamp + "_2"}, want: "pass_" + stamp + "_3"},
+		{name: "archive taken", existing: []string{"pass.tar.gz"}, archive: true, want: "pass_" + stamp},
+		{name: "archive same second", existing: []string{"pass.tar.gz", "pass_" + stamp + ".tar.gz"}, archive: true, want: "pass_" + stamp + "_2"},
+		{name: "archive ignored without compression", existing: []string{"pass.tar.gz"}, want: "pass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed := t.TempDir()
+			for _, name := range tt.existing {
+				path := filepath.Join(processed, name)
+				var err error
+				if filepath.Ext(name) == ".gz" {
+					err = os.WriteFile(path, nil, 0644)
+				} else {
+					err = os.Mkdir(path, 0755)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := uniqueDestination(filepath.Join(processed, "pass"), tt.archive, now)
+			if want := filepath.Join(processed, tt.want); got != want {
+				t.Errorf("uniqueDestination() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMoveDirectoryToProcessedKeepsExistingPass(t *testing.T) {
+	watchDir := t.TempDir()
+	processedDir := t.TempDir()
+
+	source := filepath.Join(watchDir, "pass")
+	if err := os.Mkdir(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "dataset.json"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(processedDir, "pass")
+	if err := os.Mkdir(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(existing, "dataset.json"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fw := &FileWatcher{config: &Config{ProcessedDir: processedDir}, logger: zerolog.Nop()}
+	before := time.Now().Unix()
+	dest, err := fw.moveDirectoryToProcessed(source)
+	if err != nil {
+		t.Fatalf("moveDirectoryToProcessed failed: %v", err)
+	}
+	after := time.Now().Unix()
+
+	if data, err := os.ReadFile(filepath.Join(existing, "dataset.json")); err != nil || string(data) != "old" {
+		t.Errorf("existing pass was modified: %q, %v", data, err)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Errorf("source directory still exists: %v", err)
+	}
+
+	var moved string
+	for stamp := before; stamp <= after; stamp++ {
+		candidate := fmt.Sprintf("%s_%d", existing, stamp)
+		if _, err := os.Stat(candidate); err == nil {
+			moved = candidate
+		}
+	}
+	if moved == "" {
+		t.Fatalf("pass was not moved to %s_<unix timestamp>", existing)
+	}
+	if dest != moved {
+		t.Errorf("moveDirectoryToProcessed() = %q, want %q", dest, moved)
+	}
+	if data, err := os.ReadFile(filepath.Join(moved, "dataset.json")); err != nil || string(data) != "new" {
+		t.Errorf("moved pass has dataset.json %q, %v, want new", data, err)
+	}
+}
+
+func TestToCBORTimestamp(t *testing.T) {
+	const seconds = 1700000000
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int64 // Unix seconds, ignored when ok is false
+		ok    bool
+	}{
+		{"float64", float64(seconds) + 0.5, seconds, true},
+		{"float32", float32(1 << 30), 1 << 30, true},
+		{"int", int(seconds), seconds, true},
+		{"int64", int64(seconds), seconds, true},
+		{"uint64", uint64(seconds), seconds, true},
+		{"int32", int32(seconds), seconds, true},
+		{"uint32", uint32(seconds), seconds, true},
+		{"tag 1 integer", cbor.Tag{Number: 1, Content: uint64(seconds)}, seconds, true},
+		{"tag 1 float", cbor.Tag{Number: 1, Content: float64(seconds)}, seconds, true},
+		{"time", time.Unix(seconds, 0), seconds, true},
+		{"missing float", float64(-1), 0, false},
+		{"missing int64", int64(-1), 0, false},
+		{"missing in tag 1", cbor.Tag{Number: 1, Content: int64(-1)}, 0, false},
+		{"NaN", math.NaN(), 0, false},
+		{"infinity", math.Inf(1), 0, false},
+		{"uint64 overflow", uint64(math.MaxUint64), 0, false},
+		{"tag 0", cbor.Tag{Number: 0, Content: "2023-11-14T22:13:20Z"}, 0, false},
+		{"tag 1 string", cbor.Tag{Number: 1, Content: "1700000000"}, 0, false},
+		{"string", "1700000000", 0, false},
+		{"nil", nil, 0, false},
+		{"zero time", time.Time{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := toCBORTimestamp(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("toCBORTimestamp(%#v) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if ok && got.Unix() != tt.want {
+				t.Errorf("toCBORTimestamp(%#v) = %v, want %v", tt.value, got, time.Unix(tt.want, 0))
+			}
+		})
+	}
+}
+
+func TestParseCBORTimestamps(t *testing.T) {
+	fw := &FileWatcher{logger: zerolog.Nop()}
+
+	timestamps := []interface{}{
+		float64(-1),
+		float64(1700000100.25),
+		int64(1700000000),
+		cbor.Tag{Number: 1, Content: uint64(1700000200)},
+		uint32(1700000050),
+		cbor.Tag{Number: 0, Content: "2030-01-01T00:00:00Z"},
+		"garbage",
+		int64(-1),
+	}
+	got, err := fw.parseCBORTimestamps(timestamps)
+	if err != nil {
+		t.Fatalf("parseCBORTimestamps failed: %v", err)
+	}
+	if !got.Start.Equal(time.Unix(1700000000, 0)) || !got.End.Equal(time.Unix(1700000200, 0)) {
+		t.Errorf("parseCBORTimestamps() = %v - %v, want %v - %v",
+			got.Start, got.End, time.Unix(1700000000, 0), time.Unix(1700000200, 0))
+	}
+
+	if _, err := fw.parseCBORTimestamps([]interface{}{float64(-1), "garbage"}); err == nil {
+		t.Error("parseCBORTimestamps succeeded without a valid timestamp")
+	}
+	if _, err := fw.parseCBORTimestamps(nil); err == nil {
+		t.Error("parseCBORTimestamps succeeded without timestamps")
+	}
+}
+
+// simulateCrossDevice makes renameFunc fail with EXDEV like a move to another filesystem, before failing
+// it calls prepare with the destination if given
+func simulateCrossDevice(t *testing.T, prepare func(dst string)) {
+	t.Helper()
+	renameFunc = func(src, dst string) error {
+		if prepare != nil {
+			prepare(dst)
+		}
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { renameFunc = os.Rename })
+}
+
+func TestMoveDirectoryCopiesAcrossFilesystems(t *testing.T) {
+	simulateCrossDevice(t, nil)
+
+	src := filepath.Join(t.TempDir(), "pass")
+	dst := filepath.Join(t.TempDir(), "pass")
+	files := map[string]os.FileMode{
+		"dataset.json":            0640,
+		"MSU-MR/product.cbor":     0600,
+		"MSU-MR/rgb.png":          0644,
+		"MSU-MR/nested/run.sh":    0777,
+		"MSU-MR/nested/README.md": 0444,
+	}
+	dirs := map[string]os.FileMode{
+		".":             0750,
+		"MSU-MR":        0700,
+		"MSU-MR/nested": 0777,
+	}
+	for name := range dirs {
+		if err := os.MkdirAll(filepath.Join(src, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, mode := range files {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, mode := range dirs {
+		if err := os.Chmod(filepath.Join(src, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := moveDirectory(src, dst); err != nil {
+		t.Fatalf("moveDirectory failed: %v", err)
+	}
+
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists after the copy: %v", err)
+	}
+	for name, mode := range files {
+		path := filepath.Join(dst, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("%s was not copied: %v", name, err)
+			continue
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s has mode %v, want %v", name, info.Mode().Perm(), mode)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != name {
+			t.Errorf("%s has content %q, %v", name, data, err)
+		}
+	}
+	for name, mode := range dirs {
+		info, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("directory %s was not copied: %v", name, err)
+			continue
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("directory %s has mode %v, want %v", name, info.Mode().Perm(), mode)
+		}
+	}
+}
+
+func TestMoveDirectoryRemovesPartialCopy(t *testing.T) {
+	// A directory where the copy wants to create a file makes copying fail halfway, even as root
+	simulateCrossDevice(t, func(dst string) {
+		if err := os.MkdirAll(filepath.Join(dst, "dataset.json"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	src := filepath.Join(t.TempDir(), "pass")
+	dst := filepath.Join(t.TempDir(), "pass")
+	if err := os.MkdirAll(filepath.Join(src, "MSU-MR"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"MSU-MR/rgb.png", "dataset.json"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := moveDirectory(src, dst); err == nil {
+		t.Fatal("moveDirectory succeeded although copying failed")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("partial copy was left behind: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "dataset.json")); err != nil {
+		t.Errorf("source was removed although copying failed: %v", err)
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     bool
+	}{
+		{"rgb.png", []string{"*.png"}, true},
+		{"RGB.PNG", []string{"*.png"}, true},
+		{"rgb.png", []string{"*.PNG"}, true},
+		{"avhrr_3a.Tiff", config.DefaultImageIncludePatterns, true},
+		{"avhrr_3a.TIF", config.DefaultImageIncludePatterns, true},
+		{"avhrr_3a.jpg", config.DefaultImageIncludePatterns, false},
+		{"Debug_Channel.png", []string{"debug_*"}, true},
+		{"msu_mr_rgb.png", []string{"msu_mr_?gb.png"}, true},
+		{"rgb.png", []string{"[", "*.png"}, true},
+		{"rgb.png", []string{"["}, false},
+		{"rgb.png", nil, false},
+		{"METEOR-M2 3", []string{"meteor-*"}, true},
+		{"NOAA 19", []string{"meteor-*"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesPattern(tt.name, tt.patterns); got != tt.want {
+				t.Errorf("matchesPattern(%q, %q) = %v, want %v", tt.name, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+	images := []string{
+		"/pass/MSU-MR/rgb.png",
+		"/pass/MSU-MR/RGB_221.PNG",
+		"/pass/MSU-MR/debug_raw.png",
+		"/pass/AVHRR/avhrr_3a.TIFF",
+		"/pass/AVHRR/avhrr_3a.tif",
+		"/pass/AVHRR/preview.jpg",
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name: "no patterns",
+			want: images,
+		},
+		{
+			name:    "default include patterns",
+			include: config.DefaultImageIncludePatterns,
+			want:    images[:5],
+		},
+		{
+			name:    "exclude after include",
+			include: config.DefaultImageIncludePatterns,
+			exclude: []string{"DEBUG_*"},
+			want:    []string{images[0], images[1], images[3], images[4]},
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"*.tif", "*.tiff"},
+			want:    []string{images[0], images[1], images[2], images[5]},
+		},
+		{
+			name:    "nothing included",
+			include: []string{"*.webp"},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fw := &FileWatcher{
+				config: &Config{ImageInclude: tt.include, ImageExclude: tt.exclude},
+				logger: zerolog.Nop(),
+			}
+			if got := fw.filterImages(images); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterImages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSatelliteName(t *testing.T) {
+	defaults := (&config.Config{}).SatelliteAliases()
+	custom := (&config.Config{Satellites: config.SatellitesConfig{Aliases: map[string]string{
+		"NOAA 19":      "NOAA 19 (APT)",
+		" my cubesat ": "MYSAT-1",
+	}}}).SatelliteAliases()
+
+	tests := []struct {
+		name    string
+		raw     string
+		aliases map[string]string
+		want    string
+	}{
+		{"space", "NOAA 19", defaults, "NOAA-19"},
+		{"canonical", "NOAA-19", defaults, "NOAA-19"},
+		{"lower-case without space", "noaa19", defaults, "NOAA-19"},
+		{"underscore", "NOAA_19", defaults, "NOAA-19"},
+		{"surrounding whitespace", "  noaa 19 ", defaults, "NOAA-19"},
+		{"meteor", "METEOR-M2 3", defaults, "METEOR-M2-3"},
+		{"unknown", "FENGYUN 3D", defaults, "FENGYUN 3D"},
+		{"empty", "", defaults, ""},
+		{"no aliases", "noaa19", nil, "noaa19"},
+		{"user alias overrides default", "noaa 19", custom, "NOAA 19 (APT)"},
+		{"defaults kept next to user aliases", "noaa19", custom, "NOAA-19"},
+		{"user alias key is trimmed", "My Cubesat", custom, "MYSAT-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeSatelliteName(tt.raw, tt.aliases); got != tt.want {
+				t.Errorf("NormalizeSatelliteName(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitWhilePaused(t *testing.T) {
+	tests := []struct {
+		name   string
+		pause  bool
+		finish func(fw *FileWatcher)
+		want   bool
+	}{
+		{name: "not paused", want: true},
+		{name: "resumed", pause: true, finish: (*FileWatcher).Resume, want: true},
+		{name: "stopped", pause: true, finish: func(fw *FileWatcher) { close(fw.stopCh) }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fw := &FileWatcher{stopCh: make(chan struct{}), logger: zerolog.Nop()}
+			if tt.pause {
+				fw.Pause()
+			}
+
+			done := make(chan bool, 1)
+			go func() { done <- fw.waitWhilePaused("/pass") }()
+			if tt.finish != nil {
+				select {
+				case <-done:
+					t.Fatal("waitWhilePaused returned while paused")
+				case <-time.After(50 * time.Millisecond):
+				}
+				tt.finish(fw)
+			}
+
+			select {
+			case got := <-done:
+				if got != tt.want {
+					t.Errorf("waitWhilePaused() = %v, want %v", got, tt.want)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("waitWhilePaused kept blocking")
+			}
+		})
+	}
+}
+
+func TestForceNewPostsIgnoresChecksums(t *testing.T) {
+	checksums, err := NewChecksumStore(filepath.Join(t.TempDir(), "checksums.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw := &FileWatcher{config: &Config{}, checksums: checksums, logger: zerolog.Nop()}
+	fw.saveChecksum("dataset", "post-1")
+
+	if postID, ok := fw.lookupChecksum("dataset"); !ok || postID != "post-1" {
+		t.Fatalf("lookupChecksum() = %q, %v, want post-1", postID, ok)
+	}
+
+	fw.SetForceNewPosts(true)
+	if postID, ok := fw.lookupChecksum("dataset"); ok {
+		t.Errorf("lookupChecksum() = %q with forced new posts, want no post", postID)
+	}
+
+	// The post created instead replaces the old one
+	fw.saveChecksum("dataset", "post-2")
+	fw.SetForceNewPosts(false)
+	if postID, _ := fw.lookupChecksum("dataset"); postID != "post-2" {
+		t.Errorf("lookupChecksum() = %q after uploading again, want post-2", postID)
+	}
+}
+
+// chanWatcher is a dirWatcher fed by the test
+type chanWatcher struct {
+	events chan fsnotify.Event
+	errors chan error
+}
+
+func (w *chanWatcher) Add(path string) error         { return nil }
+func (w *chanWatcher) Close() error                  { return nil }
+func (w *chanWatcher) Events() <-chan fsnotify.Event { return w.events }
+func (w *chanWatcher) Errors() <-chan error          { return w.errors }
+
+func TestMovedPassIsNoLongerProcessed(t *testing.T) {
+	watch := t.TempDir()
+	events := make(chan fsnotify.Event)
+	fw := &FileWatcher{
+		config:    &Config{},
+		watcher:   &chanWatcher{events: events, errors: make(chan error)},
+		processed: make(map[string]bool),
+		logger:    zerolog.Nop(),
+	}
+
+	moved := filepath.Join(watch, "moved")
+	removed := filepath.Join(watch, "removed")
+	kept := filepath.Join(watch, "kept")
+	for _, dir := range []string{moved, removed, kept} {
+		fw.markProcessed(dir)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		fw.watchEvents()
+		close(done)
+	}()
+	events <- fsnotify.Event{Name: moved, Op: fsnotify.Rename}
+	events <- fsnotify.Event{Name: removed, Op: fsnotify.Remove}
+	events <- fsnotify.Event{Name: filepath.Join(kept, "rgb.png"), Op: fsnotify.Write}
+	close(events)
+	<-done
+
+	if fw.isProcessed(moved) || fw.isProcessed(removed) {
+		t.Error("a pass moved out of the watch directory is still marked as processed")
+	}
+	if !fw.isProcessed(kept) {
+		t.Error("a pass that is still in the watch directory was unmarked")
+	}
+}
+
+func TestDeadLetterKeepsExistingPass(t *testing.T) {
+	root := t.TempDir()
+	deadLetter := filepath.Join(root, "dead-letter")
+	fw := &FileWatcher{config: &Config{DeadLetterDir: deadLetter}, logger: zerolog.Nop()}
+
+	for i := 0; i < 2; i++ {
+		pass := filepath.Join(root, "watch", "pass")
+		if err := os.MkdirAll(pass, 0755); err != nil {
+			t.Fatal(err)
+		}
+		fw.deadLetter(pass, &RetryEntry{Path: pass, AttemptCount: i + 1})
+		if _, err := os.Stat(pass); !os.IsNotExist(err) {
+			t.Fatalf("attempt %d: pass was left in the watch directory", i+1)
+		}
+	}
+
+	entries, err := os.ReadDir(deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("dead-letter directory has %d passes, want 2", len(entries))
+	}
+}
+
+func TestScanReportsUnreadableWatchDirectory(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	fw := &FileWatcher{
+		config:    &Config{WatchPaths: []string{missing}, RecursiveDepth: 1},
+		processed: make(map[string]bool),
+		logger:    zerolog.Nop(),
+	}
+
+	errs := fw.scanExistingDirectories(true)
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("scanExistingDirectories() = %v, want the error reading %s", errs, missing)
+	}
+}
+
+func TestAuthFailuresDontCountAsRetries(t *testing.T) {
+	retries, err := NewRetryQueue(filepath.Join(t.TempDir(), "retry-queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw := &FileWatcher{config: &Config{MaxRetries: 2}, retries: retries, logger: zerolog.Nop()}
+	pass := "/watch/pass"
+
+	for i := 0; i < 3; i++ {
+		err := &APIError{StatusCode: http.StatusUnauthorized, Body: "invalid token"}
+		fw.updateRetryQueue(pass, &PassResult{FailedStep: stepCreatePost}, fmt.Errorf("failed to create post: %w", err))
+	}
+	if fw.retriesExhausted(pass) {
+		t.Fatal("rejected tokens used up the retries of the pass")
+	}
+
+	fw.updateRetryQueue(pass, &PassResult{FailedStep: stepCreatePost}, &APIError{StatusCode: http.StatusBadGateway})
+	entry, _ := fw.retries.Get(pass)
+	if entry.AttemptCount != 1 {
+		t.Errorf("attempt count = %d after one server error, want 1", entry.AttemptCount)
+	}
+}
+
+func TestMoveDirectoryToProcessedReportsFailure(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pass")
+	if err := os.Mkdir(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// The processed directory can't be created below a file
+	blocker := filepath.Join(root, "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fw := &FileWatcher{config: &Config{ProcessedDir: filepath.Join(blocker, "processed")}, logger: zerolog.Nop()}
+	if dest, err := fw.moveDirectoryToProcessed(source); err == nil {
+		t.Errorf("moveDirectoryToProcessed() = %q, want an error", dest)
+	}
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("source directory is gone after a failed move: %v", err)
+	}
+}
@@ -1,17 +1,20 @@
 package main
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"path"
 	"sathub-client/config"
+	"sathub-client/metrics"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -29,6 +32,10 @@ const (
 	MessageTypeSettingsUpdate = "settings_update"
 	MessageTypeRestartCommand = "restart_command"
 	MessageTypeStatusUpdate   = "status_update"
+	MessageTypeForceScan      = "force_scan"
+	MessageTypePause          = "pause"
+	MessageTypeResume         = "resume"
+	MessageTypeLogLevelChange = "log_level_change"
 )
 
 // SettingsUpdatePayload for settings_update messages from server
@@ -37,16 +44,33 @@ type SettingsUpdatePayload struct {
 	ProcessDelay        int `json:"process_delay"`
 }
 
+// LogLevelPayload for log_level_change messages from server
+type LogLevelPayload struct {
+	Level string `json:"level"`
+}
+
 // StatusUpdatePayload for status_update messages to server
 type StatusUpdatePayload struct {
-	Version string                 `json:"version"`
-	Uptime  int64                  `json:"uptime"` // seconds
-	Config  map[string]interface{} `json:"config"`
+	Version                 string                 `json:"version"`
+	Uptime                  int64                  `json:"uptime"` // seconds
+	LogLevel                string                 `json:"log_level"`
+	MaxUploadBytesPerSecond int64                  `json:"max_upload_bytes_per_second"` // 0 for unlimited
+	DiskFreeMB              int64                  `json:"disk_free_mb"`                // free space on the watch partition, -1 if unknown
+	Error                   string                 `json:"error,omitempty"`
+	Config                  map[string]interface{} `json:"config"`
+	WSReconnectCount        int                    `json:"ws_reconnect_count"` // reconnect attempts since startup
+	WSLastDisconnectAt      *time.Time             `json:"ws_last_disconnect_at,omitempty"`
+	WSLastDisconnectReason  string                 `json:"ws_last_disconnect_reason,omitempty"`
+	APIErrorCount           int                    `json:"api_error_count"`  // failed API requests since the last successful health check
+	ProcessedPasses         int                    `json:"processed_passes"` // passes uploaded successfully according to the pass history, -1 if unknown
+	// Pass counters of the watcher since startup, nil without a watcher
+	Watcher *WatcherStats `json:"watcher,omitempty"`
 }
 
 // WSClient manages the WebSocket connection to the backend
 type WSClient struct {
 	cfg              *config.Config
+	station          config.StationConfig // Token, API URL and watch path of the connected station
 	configPath       string
 	stationID        string
 	conn             *websocket.Conn
@@ -58,14 +82,35 @@ type WSClient struct {
 	sendChan         chan WSMessage
 	connected        bool
 	startTime        time.Time
+	metrics          *metrics.Collector
+	apiClient        *APIClient   // Source of the API error count in status updates, may be nil
+	watcher          *FileWatcher // Source of the processed pass count in status updates, may be nil
 	onSettingsUpdate func(*SettingsUpdatePayload)
 	onRestart        func()
+	onForceScan      func()
+	onPause          func()
+	onResume         func()
+	onConnect        func()
+	onDisconnect     func(error)
+
+	// MaxReconnectAttempts is how many reconnects in a row may fail before the client gives up on
+	// the WebSocket and keeps running without it, 0 retries forever
+	MaxReconnectAttempts int
+
+	// Connection quality, protected by mu
+	reconnectCount       int
+	lastDisconnectAt     *time.Time
+	lastDisconnectReason string
+	lastDisconnectErr    error
+
+	tlsHintOnce sync.Once // Certificate errors are explained on the first failed attempt only
 }
 
 // NewWSClient creates a new WebSocket client
 func NewWSClient(cfg *config.Config, configPath string, stationID string) *WSClient {
 	return &WSClient{
 		cfg:              cfg,
+		station:          cfg.PrimaryStation(),
 		configPath:       configPath,
 		stationID:        stationID,
 		reconnectDelay:   5 * time.Second,
@@ -76,6 +121,11 @@ func NewWSClient(cfg *config.Config, configPath string, stationID string) *WSCli
 	}
 }
 
+// SetStation sets the station to connect as, the primary station of the config by default
+func (ws *WSClient) SetStation(station config.StationConfig) {
+	ws.station = station
+}
+
 // SetOnSettingsUpdate sets the callback for settings updates
 func (ws *WSClient) SetOnSettingsUpdate(callback func(*SettingsUpdatePayload)) {
 	ws.onSettingsUpdate = callback
@@ -86,6 +136,46 @@ func (ws *WSClient) SetOnRestart(callback func()) {
 	ws.onRestart = callback
 }
 
+// SetOnForceScan sets the callback for force scan commands
+func (ws *WSClient) SetOnForceScan(callback func()) {
+	ws.onForceScan = callback
+}
+
+// SetOnPause sets the callback for pause commands
+func (ws *WSClient) SetOnPause(callback func()) {
+	ws.onPause = callback
+}
+
+// SetOnResume sets the callback for resume commands
+func (ws *WSClient) SetOnResume(callback func()) {
+	ws.onResume = callback
+}
+
+// SetOnConnect sets the callback run after each successful connection, including reconnects
+func (ws *WSClient) SetOnConnect(callback func()) {
+	ws.onConnect = callback
+}
+
+// SetOnDisconnect sets the callback run with the reason when an established connection is lost
+func (ws *WSClient) SetOnDisconnect(callback func(error)) {
+	ws.onDisconnect = callback
+}
+
+// SetMetrics sets the optional collector used to record connection metrics
+func (ws *WSClient) SetMetrics(collector *metrics.Collector) {
+	ws.metrics = collector
+}
+
+// SetAPIClient sets the API client whose error count is reported in status updates
+func (ws *WSClient) SetAPIClient(apiClient *APIClient) {
+	ws.apiClient = apiClient
+}
+
+// SetWatcher sets the watcher whose processed pass count is reported in status updates
+func (ws *WSClient) SetWatcher(watcher *FileWatcher) {
+	ws.watcher = watcher
+}
+
 // Connect establishes the WebSocket connection
 func (ws *WSClient) Connect() error {
 	// Build WebSocket URL from API URL
@@ -96,21 +186,30 @@ func (ws *WSClient) Connect() error {
 
 	// Create HTTP header with station token
 	header := http.Header{}
-	header.Set("Authorization", fmt.Sprintf("Station %s", ws.cfg.Station.Token))
+	header.Set("Authorization", fmt.Sprintf("Station %s", ws.station.Token))
+	header.Set("User-Agent", defaultUserAgent())
 
 	// Create dialer with TLS config
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
 	}
 
-	if ws.cfg.Options.Insecure {
-		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	tlsConfig, err := buildTLSConfig(ws.cfg.Options)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
 	}
+	dialer.TLSClientConfig = tlsConfig
 
 	// Connect to WebSocket
 	log.Info().Str("url", wsURL).Msg("Connecting to WebSocket")
 	conn, _, err := dialer.Dial(wsURL, header)
 	if err != nil {
+		if !ws.cfg.Options.Insecure && isCertificateError(err) {
+			// Reconnects fail the same way, explain it once
+			ws.tlsHintOnce.Do(func() {
+				log.Warn().Err(err).Msg("The server certificate was not accepted. For a self-signed certificate set options.tls_ca_cert to the CA that signed it, or options.insecure: true to skip verification")
+			})
+		}
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
@@ -118,6 +217,7 @@ func (ws *WSClient) Connect() error {
 	ws.conn = conn
 	ws.connected = true
 	ws.mu.Unlock()
+	ws.metrics.SetWebSocketConnected(true)
 
 	log.Info().Msg("WebSocket connection established")
 
@@ -135,7 +235,17 @@ func (ws *WSClient) Start() {
 
 // connectWithRetry handles connection with exponential backoff
 func (ws *WSClient) connectWithRetry() {
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(log.Logger, "connectWithRetry", r)
+			// Start over after the base delay, the loop returns right away if the client was stopped
+			time.AfterFunc(ws.reconnectDelay, ws.connectWithRetry)
+		}
+	}()
+
 	delay := ws.reconnectDelay
+	attempted := false
+	failures := 0 // Attempts since the last connection that lasted longer than reconnectDelay
 
 	for {
 		select {
@@ -144,28 +254,76 @@ func (ws *WSClient) connectWithRetry() {
 		default:
 		}
 
+		// Every attempt after the first is a reconnect
+		if attempted {
+			ws.mu.Lock()
+			ws.reconnectCount++
+			ws.mu.Unlock()
+		}
+		attempted = true
+
 		err := ws.Connect()
 		if err == nil {
-			// Reset delay on successful connection
-			delay = ws.reconnectDelay
+			connectedAt := time.Now()
+			if ws.onConnect != nil {
+				ws.onConnect()
+			}
 			// Wait for disconnection or stop signal
 			ws.waitForDisconnect()
-		} else {
-			log.Warn().Err(err).Dur("retry_in", delay).Msg("Failed to connect to WebSocket, retrying")
 
 			select {
 			case <-ws.stopChan:
 				return
-			case <-time.After(delay):
+			default:
 			}
-
-			// Exponential backoff
-			delay *= 2
-			if delay > ws.maxReconnectWait {
-				delay = ws.maxReconnectWait
+			if ws.onDisconnect != nil {
+				ws.mu.RLock()
+				reason := ws.lastDisconnectErr
+				ws.mu.RUnlock()
+				ws.onDisconnect(reason)
 			}
+			if time.Since(connectedAt) > ws.reconnectDelay {
+				// Reset delay and attempts after a lasting connection
+				delay = ws.reconnectDelay
+				failures = 0
+				continue
+			}
+			// A connection that drops right away counts as a failed attempt
+		}
+
+		if err != nil {
+			ws.recordDisconnect(err)
+		}
+		failures++
+		if ws.MaxReconnectAttempts > 0 && failures > ws.MaxReconnectAttempts {
+			// Not Fatal, which would exit: health checks and uploads keep working without the WebSocket
+			log.WithLevel(zerolog.FatalLevel).
+				Int("attempts", failures).
+				Msg("Giving up on the WebSocket connection, continuing without it")
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Dur("retry_in", delay).Msg("Failed to connect to WebSocket, retrying")
+		}
+
+		select {
+		case <-ws.stopChan:
+			return
+		case <-time.After(delay):
 		}
+
+		delay = ws.nextReconnectDelay(delay)
+	}
+}
+
+// nextReconnectDelay doubles delay up to maxReconnectWait and applies ±25% jitter, so clients
+// that lost their connection at the same time don't all reconnect at the same time
+func (ws *WSClient) nextReconnectDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > ws.maxReconnectWait {
+		delay = ws.maxReconnectWait
 	}
+	return time.Duration(float64(delay) * (0.75 + rand.Float64()*0.5))
 }
 
 // waitForDisconnect blocks until connection is lost or stop signal received
@@ -203,11 +361,22 @@ func (ws *WSClient) Stop() {
 		}
 		ws.connected = false
 		ws.mu.Unlock()
+		ws.metrics.SetWebSocketConnected(false)
 
 		log.Info().Msg("WebSocket connection closed")
 	})
 }
 
+// recordDisconnect stores when and why the connection was lost or could not be established
+func (ws *WSClient) recordDisconnect(reason error) {
+	now := time.Now()
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.lastDisconnectAt = &now
+	ws.lastDisconnectReason = reason.Error()
+	ws.lastDisconnectErr = reason
+}
+
 // IsConnected returns whether the WebSocket is currently connected
 func (ws *WSClient) IsConnected() bool {
 	ws.mu.RLock()
@@ -227,15 +396,64 @@ func (ws *WSClient) Send(msg WSMessage) {
 
 // SendStatusUpdate sends a status update to the server
 func (ws *WSClient) SendStatusUpdate() {
+	ws.sendStatus("")
+}
+
+// SendStatusError sends a status update carrying an error the server should alert the operator about
+func (ws *WSClient) SendStatusError(message string) {
+	ws.sendStatus(message)
+}
+
+// sendStatus sends a status update with an optional error message
+func (ws *WSClient) sendStatus(errMessage string) {
 	uptime := int64(time.Since(ws.startTime).Seconds())
 
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+
+	diskFreeMB := int64(-1)
+	if free, err := diskFreeBytes(nearestExistingDir(ws.station.Watch)); err == nil {
+		diskFreeMB = int64(free / 1024 / 1024)
+	}
+
+	ws.mu.RLock()
+	reconnectCount := ws.reconnectCount
+	lastDisconnectAt := ws.lastDisconnectAt
+	lastDisconnectReason := ws.lastDisconnectReason
+	ws.mu.RUnlock()
+
+	apiErrorCount := 0
+	if ws.apiClient != nil {
+		apiErrorCount = ws.apiClient.ErrorCount()
+	}
+
+	processedPasses := -1
+	var watcherStats *WatcherStats
+	if ws.watcher != nil {
+		if count, err := ws.watcher.ProcessedPassCount(); err == nil {
+			processedPasses = count
+		}
+		stats := ws.watcher.Stats()
+		watcherStats = &stats
+	}
+
 	payload := StatusUpdatePayload{
-		Version: VERSION,
-		Uptime:  uptime,
+		Version:                 VERSION,
+		Uptime:                  uptime,
+		LogLevel:                zerolog.GlobalLevel().String(),
+		MaxUploadBytesPerSecond: ws.cfg.Options.MaxUploadBytesPerSecond,
+		DiskFreeMB:              diskFreeMB,
+		Error:                   errMessage,
 		Config: map[string]interface{}{
 			"health_check_interval": ws.cfg.Intervals.HealthCheck,
 			"process_delay":         ws.cfg.Intervals.ProcessDelay,
 		},
+		WSReconnectCount:       reconnectCount,
+		WSLastDisconnectAt:     lastDisconnectAt,
+		WSLastDisconnectReason: lastDisconnectReason,
+		APIErrorCount:          apiErrorCount,
+		ProcessedPasses:        processedPasses,
+		Watcher:                watcherStats,
 	}
 
 	payloadJSON, err := json.Marshal(payload)
@@ -254,12 +472,17 @@ func (ws *WSClient) SendStatusUpdate() {
 // readPump reads messages from the WebSocket
 func (ws *WSClient) readPump() {
 	defer func() {
+		// A panicking message handler drops the connection, connectWithRetry then reconnects
+		if r := recover(); r != nil {
+			logPanic(log.Logger, "readPump", r)
+		}
 		ws.mu.Lock()
 		ws.connected = false
 		if ws.conn != nil {
 			ws.conn.Close()
 		}
 		ws.mu.Unlock()
+		ws.metrics.SetWebSocketConnected(false)
 	}()
 
 	ws.conn.SetReadDeadline(time.Now().Add(90 * time.Second))
@@ -290,6 +513,7 @@ func (ws *WSClient) readPump() {
 		var msg WSMessage
 		err := ws.conn.ReadJSON(&msg)
 		if err != nil {
+			ws.recordDisconnect(err)
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Warn().Err(err).Msg("WebSocket unexpected close")
 			} else {
@@ -308,6 +532,11 @@ func (ws *WSClient) writePump() {
 	defer func() {
 		ticker.Stop()
 		ws.mu.Lock()
+		if r := recover(); r != nil {
+			logPanic(log.Logger, "writePump", r)
+			// Without a writer the connection is useless, let connectWithRetry reconnect
+			ws.connected = false
+		}
 		if ws.conn != nil {
 			ws.conn.Close()
 		}
@@ -384,6 +613,56 @@ func (ws *WSClient) handleMessage(msg WSMessage) {
 			ws.onRestart()
 		}
 
+	case MessageTypeForceScan:
+		log.Info().Msg("Received force scan command from server")
+
+		// Call callback if set
+		if ws.onForceScan != nil {
+			ws.onForceScan()
+		}
+
+	case MessageTypePause:
+		log.Info().Msg("Received pause command from server")
+
+		// Call callback if set
+		if ws.onPause != nil {
+			ws.onPause()
+		}
+
+	case MessageTypeResume:
+		log.Info().Msg("Received resume command from server")
+
+		// Call callback if set
+		if ws.onResume != nil {
+			ws.onResume()
+		}
+
+	case MessageTypeLogLevelChange:
+		var payload LogLevelPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			log.Error().Err(err).Msg("Failed to parse log level change")
+			return
+		}
+
+		// Only applied in memory, the config file is left untouched
+		var level zerolog.Level
+		switch strings.ToLower(payload.Level) {
+		case "debug":
+			level = zerolog.DebugLevel
+		case "info":
+			level = zerolog.InfoLevel
+		case "warn":
+			level = zerolog.WarnLevel
+		case "error":
+			level = zerolog.ErrorLevel
+		default:
+			log.Warn().Str("level", payload.Level).Msg("Unsupported log level")
+			return
+		}
+
+		zerolog.SetGlobalLevel(level)
+		log.Info().Str("level", level.String()).Msg("Log level changed by server")
+
 	default:
 		log.Warn().Str("type", msg.Type).Msg("Unknown WebSocket message type")
 	}
@@ -391,7 +670,7 @@ func (ws *WSClient) handleMessage(msg WSMessage) {
 
 // buildWebSocketURL constructs the WebSocket URL from the API URL
 func (ws *WSClient) buildWebSocketURL() (string, error) {
-	apiURL := ws.cfg.Station.APIURL
+	apiURL := ws.station.APIURL
 
 	// Parse the API URL
 	u, err := url.Parse(apiURL)
@@ -408,11 +687,10 @@ func (ws *WSClient) buildWebSocketURL() (string, error) {
 		return "", fmt.Errorf("unsupported API URL scheme: %s", u.Scheme)
 	}
 
-	// Build WebSocket path
-	wsPath := fmt.Sprintf("/api/stations/%s/ws", ws.stationID)
-
-	// Remove any existing path and set our WebSocket path
-	u.Path = strings.TrimSuffix(u.Path, "/") + wsPath
+	// Append the WebSocket path to the base path, e.g. /sathub/ behind a reverse proxy becomes
+	// /sathub/api/stations/<id>/ws. Join also drops duplicate and trailing slashes.
+	u.Path = path.Join("/", u.Path, "api/stations", ws.stationID, "ws")
+	u.RawPath = ""
 
 	return u.String(), nil
 }